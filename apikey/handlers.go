@@ -0,0 +1,66 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+
+	goerrors "github.com/go-openapi/errors"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/admin"
+	"github.com/movieManagement/swagger"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Configure authenticates the X-API-Key header of the secured operations and configures the API
+// key administration
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.APIKeyAuth = func(key string) (*models.Principal, error) {
+		principal, err := service.Authenticate(context.Background(), key)
+		if errors.Cause(err) == errs.ErrUnauthorized {
+			logrus.Debugf("API key authentication failed: %v", err)
+			return nil, goerrors.New(http.StatusUnauthorized, "%s", err.Error())
+		}
+		if err != nil {
+			logrus.Errorf("API key authentication failed: %v", err)
+			return nil, goerrors.New(http.StatusInternalServerError, "API key authentication unavailable")
+		}
+		return principal, nil
+	}
+
+	api.AdminCreateAPIKeyHandler = admin.CreateAPIKeyHandlerFunc(func(params admin.CreateAPIKeyParams, principal *models.Principal) middleware.Responder {
+		result, err := service.CreateAPIKey(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("CreateAPIKey :: ", err)
+		}
+		return admin.NewCreateAPIKeyCreated().WithPayload(result)
+	})
+
+	api.AdminListAPIKeysHandler = admin.ListAPIKeysHandlerFunc(func(params admin.ListAPIKeysParams, principal *models.Principal) middleware.Responder {
+		result, err := service.ListAPIKeys(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ListAPIKeys :: ", err)
+		}
+		return admin.NewListAPIKeysOK().WithPayload(result)
+	})
+
+	api.AdminRotateAPIKeyHandler = admin.RotateAPIKeyHandlerFunc(func(params admin.RotateAPIKeyParams, principal *models.Principal) middleware.Responder {
+		result, err := service.RotateAPIKey(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("RotateAPIKey :: ", err)
+		}
+		return admin.NewRotateAPIKeyOK().WithPayload(result)
+	})
+
+	api.AdminRevokeAPIKeyHandler = admin.RevokeAPIKeyHandlerFunc(func(params admin.RevokeAPIKeyParams, principal *models.Principal) middleware.Responder {
+		err := service.RevokeAPIKey(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("RevokeAPIKey :: ", err)
+		}
+		return admin.NewRevokeAPIKeyNoContent()
+	})
+}
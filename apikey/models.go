@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/lib/pq"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyPrefix marks the keys of the service, e.g. for secret scanners
+	keyPrefix = "mvk_"
+	// keyBytes is the number of random bytes of a key
+	keyBytes = 32
+	// displayLength is the number of leading characters of a key stored to recognize it
+	displayLength = 12
+)
+
+// SQLAPIKey . . .
+type SQLAPIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	Scopes     pq.StringArray
+	CreatedBy  string
+	CreatedAt  strfmt.DateTime
+	RotatedAt  pq.NullTime
+	LastUsedAt pq.NullTime
+	RevokedAt  pq.NullTime
+}
+
+func (sql *SQLAPIKey) toAPIKey() *models.APIKey {
+	key := models.APIKey{
+		ID:        sql.ID,
+		Name:      sql.Name,
+		Prefix:    sql.Prefix,
+		Scopes:    []string(sql.Scopes),
+		CreatedBy: sql.CreatedBy,
+		CreatedAt: sql.CreatedAt,
+	}
+	key.RotatedAt = nullDateTime(sql.RotatedAt)
+	key.LastUsedAt = nullDateTime(sql.LastUsedAt)
+	key.RevokedAt = nullDateTime(sql.RevokedAt)
+	return &key
+}
+
+func nullDateTime(value pq.NullTime) *strfmt.DateTime {
+	if !value.Valid {
+		return nil
+	}
+	dateTime := strfmt.DateTime(value.Time)
+	return &dateTime
+}
+
+// secret is a new API key with the values stored for it
+type secret struct {
+	key    string
+	prefix string
+	hash   string
+}
+
+// newSecret generates a random API key
+func newSecret() (*secret, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "newSecret")
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return &secret{
+		key:    key,
+		prefix: key[:displayLength],
+		hash:   hashKey(key),
+	}, nil
+}
+
+// hashKey returns the stored hash of the key. The keys are random, unlike passwords, so a fast
+// hash is as safe as a slow one and lets the keys be looked up by hash
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,187 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// KeyTable . . .
+	KeyTable = "public.api_keys"
+)
+
+var keyReturnFields = []string{
+	"id as ID",
+	"name as Name",
+	"prefix as Prefix",
+	"scopes as Scopes",
+	"created_by as CreatedBy",
+	"created_at as CreatedAt",
+	"rotated_at as RotatedAt",
+	"last_used_at as LastUsedAt",
+	"revoked_at as RevokedAt",
+}
+
+// Repository interface includes a list of supported API key operations
+type Repository interface {
+	CreateKey(ctx context.Context, name string, scopes []string, createdBy string, s *secret) (*models.APIKey, error)
+	ListKeys(ctx context.Context, includeRevoked bool) ([]*models.APIKey, error)
+	RotateKey(ctx context.Context, id int64, s *secret) (*models.APIKey, error)
+	RevokeKey(ctx context.Context, id int64) error
+	FindKey(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchKey(ctx context.Context, id int64) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// CreateKey stores the hash of the new key
+func (repo *repository) CreateKey(ctx context.Context, name string, scopes []string, createdBy string, s *secret) (*models.APIKey, error) {
+	logrus.Debugf("CreateKey repo")
+	sqlKey := SQLAPIKey{}
+
+	err := sqlz.Newx(repo.GetDB()).
+		InsertInto(KeyTable).
+		ValueMap(map[string]interface{}{
+			"name":       name,
+			"prefix":     s.prefix,
+			"key_hash":   s.hash,
+			"scopes":     pq.StringArray(scopes),
+			"created_by": createdBy,
+		}).
+		Returning(keyReturnFields...).
+		GetRow(&sqlKey)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "CreateKey.InsertQuery")
+	}
+
+	return sqlKey.toAPIKey(), nil
+}
+
+// ListKeys returns the keys, the most recently created first
+func (repo *repository) ListKeys(ctx context.Context, includeRevoked bool) ([]*models.APIKey, error) {
+	logrus.Debugf("ListKeys repo")
+	sqlKeys := []SQLAPIKey{}
+	conditions := []sqlz.WhereCondition{}
+	if !includeRevoked {
+		conditions = append(conditions, sqlz.IsNull("revoked_at"))
+	}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Select(keyReturnFields...).
+		From(KeyTable).
+		Where(conditions...).
+		OrderBy(sqlz.Desc("id")).
+		GetAll(&sqlKeys)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "ListKeys.SelectQuery")
+	}
+
+	keys := make([]*models.APIKey, 0, len(sqlKeys))
+	for _, sqlKey := range sqlKeys {
+		keys = append(keys, sqlKey.toAPIKey())
+	}
+	return keys, nil
+}
+
+// RotateKey replaces the hash of the key, errs.ErrNotFound when the key is unknown or revoked
+func (repo *repository) RotateKey(ctx context.Context, id int64, s *secret) (*models.APIKey, error) {
+	logrus.Debugf("RotateKey repo")
+	sqlKey := SQLAPIKey{}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Update(KeyTable).
+		Set("prefix", s.prefix).
+		Set("key_hash", s.hash).
+		Set("rotated_at", sqlz.Indirect("now()")).
+		Where(sqlz.Eq("id", id), sqlz.IsNull("revoked_at")).
+		Returning(keyReturnFields...).
+		GetRow(&sqlKey)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "RotateKey")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "RotateKey.UpdateQuery")
+	}
+
+	return sqlKey.toAPIKey(), nil
+}
+
+// RevokeKey revokes the key, revoking a revoked key again keeps its revocation time
+func (repo *repository) RevokeKey(ctx context.Context, id int64) error {
+	logrus.Debugf("RevokeKey repo")
+	res, err := sqlz.Newx(repo.GetDB()).
+		Update(KeyTable).
+		Set("revoked_at", sqlz.Indirect("COALESCE(revoked_at, now())")).
+		Where(sqlz.Eq("id", id)).
+		Exec()
+	if err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "RevokeKey.UpdateQuery")
+	}
+
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RevokeKey.RowsAffected")
+	}
+	if revoked == 0 {
+		return errors.Wrap(errs.ErrNotFound, "RevokeKey")
+	}
+	return nil
+}
+
+// FindKey returns the unrevoked key with the hash, errs.ErrNotFound when there is none
+func (repo *repository) FindKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	sqlKey := SQLAPIKey{}
+	err := sqlz.Newx(repo.GetDB()).
+		Select(keyReturnFields...).
+		From(KeyTable).
+		Where(sqlz.Eq("key_hash", hash), sqlz.IsNull("revoked_at")).
+		GetRow(&sqlKey)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "FindKey")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "FindKey.SelectQuery")
+	}
+
+	return sqlKey.toAPIKey(), nil
+}
+
+// TouchKey records the use of the key, at most once a minute
+func (repo *repository) TouchKey(ctx context.Context, id int64) error {
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(KeyTable).
+		Set("last_used_at", sqlz.Indirect("now()")).
+		Where(sqlz.Eq("id", id), sqlz.SQLCond("(last_used_at IS NULL OR last_used_at < now() - interval '1 minute')")).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "TouchKey.UpdateQuery")
+	}
+	return nil
+}
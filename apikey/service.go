@@ -0,0 +1,177 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/swag"
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/admin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Service interface is a list of services for the API key administration and authentication
+type Service interface {
+	CreateAPIKey(ctx context.Context, in *admin.CreateAPIKeyParams) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, in *admin.ListAPIKeysParams) (models.APIKeyList, error)
+	RotateAPIKey(ctx context.Context, in *admin.RotateAPIKeyParams) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, in *admin.RevokeAPIKeyParams) error
+	Authenticate(ctx context.Context, key string) (*models.Principal, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// New is a simple helper function to create a service instance
+func New(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// CreateAPIKey service definition
+func (s *service) CreateAPIKey(ctx context.Context, in *admin.CreateAPIKeyParams) (*models.APIKey, error) {
+	log.Debugf("entered service CreateAPIKey")
+	if err := auth.Authorize(ctx, auth.AdministerAPIKeys); err != nil {
+		return nil, errors.Wrap(err, "service.CreateAPIKey")
+	}
+
+	name := strings.TrimSpace(swag.StringValue(in.APIKey.Name))
+	if name == "" {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.CreateAPIKey: Name is required")
+	}
+	scopes, err := validScopes(in.APIKey.Scopes)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.CreateAPIKey")
+	}
+
+	createdBy := ""
+	if principal, ok := auth.FromContext(ctx); ok {
+		createdBy = principal.Subject
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "service.CreateAPIKey")
+	}
+	key, err := s.repo.CreateKey(ctx, name, scopes, createdBy, secret)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.CreateAPIKey")
+	}
+
+	logrus.Infof("API key %d %q created by %s with scopes %v", key.ID, key.Name, createdBy, key.Scopes)
+	key.Key = secret.key
+	return key, nil
+}
+
+// ListAPIKeys service definition
+func (s *service) ListAPIKeys(ctx context.Context, in *admin.ListAPIKeysParams) (models.APIKeyList, error) {
+	log.Debugf("entered service ListAPIKeys")
+	if err := auth.Authorize(ctx, auth.AdministerAPIKeys); err != nil {
+		return nil, errors.Wrap(err, "service.ListAPIKeys")
+	}
+
+	keys, err := s.repo.ListKeys(ctx, swag.BoolValue(in.IncludeRevoked))
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ListAPIKeys")
+	}
+	return keys, nil
+}
+
+// RotateAPIKey service definition
+func (s *service) RotateAPIKey(ctx context.Context, in *admin.RotateAPIKeyParams) (*models.APIKey, error) {
+	log.Debugf("entered service RotateAPIKey")
+	if err := auth.Authorize(ctx, auth.AdministerAPIKeys); err != nil {
+		return nil, errors.Wrap(err, "service.RotateAPIKey")
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "service.RotateAPIKey")
+	}
+	key, err := s.repo.RotateKey(ctx, in.ID, secret)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.RotateAPIKey")
+	}
+
+	logrus.Infof("API key %d %q rotated", key.ID, key.Name)
+	key.Key = secret.key
+	return key, nil
+}
+
+// RevokeAPIKey service definition
+func (s *service) RevokeAPIKey(ctx context.Context, in *admin.RevokeAPIKeyParams) error {
+	log.Debugf("entered service RevokeAPIKey")
+	if err := auth.Authorize(ctx, auth.AdministerAPIKeys); err != nil {
+		return errors.Wrap(err, "service.RevokeAPIKey")
+	}
+
+	if err := s.repo.RevokeKey(ctx, in.ID); err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "service.RevokeAPIKey")
+	}
+
+	logrus.Infof("API key %d revoked", in.ID)
+	return nil
+}
+
+// Authenticate returns the principal of the API key, errs.ErrUnauthorized when the key is
+// unknown or revoked. The use of the key is recorded
+func (s *service) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "malformed API key")
+	}
+
+	found, err := s.repo.FindKey(ctx, hashKey(key))
+	if errors.Cause(err) == errs.ErrNotFound {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "invalid API key")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "service.Authenticate")
+	}
+
+	if err := s.repo.TouchKey(ctx, found.ID); err != nil {
+		logrus.Warnf("recording the use of API key %d failed: %v", found.ID, err)
+	}
+
+	return &models.Principal{
+		Kind:    auth.KindAPIKey,
+		Subject: fmt.Sprintf("api-key:%d", found.ID),
+		Scopes:  found.Scopes,
+		Roles:   auth.RolesOfScopes(found.Scopes),
+		Claims:  map[string]interface{}{"name": found.Name},
+	}, nil
+}
+
+// validScopes checks the scopes and removes the duplicates
+func validScopes(scopes []string) ([]string, error) {
+	valid := []string{}
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		known := false
+		for _, s := range auth.Scopes {
+			known = known || s == scope
+		}
+		if !known {
+			return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("unknown scope %q", scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, errors.Wrap(errs.ErrInvalid, "at least one scope is required")
+	}
+	return valid, nil
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/movieManagement/gen/models"
+)
+
+type principalKey struct{}
+
+// NewContext returns the context carrying the authenticated principal, ctx itself when nil
+func NewContext(ctx context.Context, principal *models.Principal) context.Context {
+	if principal == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated principal of the context, false for anonymous calls
+func FromContext(ctx context.Context) (*models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*models.Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"net/http"
+
+	goerrors "github.com/go-openapi/errors"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/sirupsen/logrus"
+)
+
+// Configure authenticates the lf-auth bearer tokens of the secured operations with the verifier,
+// and authorizes the API keys by scope. The handlers put the principal on the context of the
+// service calls, see NewContext
+func Configure(api *operations.MovieServiceAPI, verifier *Verifier) {
+	if !verifier.Configured() {
+		logrus.Warnf("No JWT secret or key configured, the authenticated operations reject every request")
+	}
+
+	api.LfAuthAuth = func(header string) (*models.Principal, error) {
+		principal, err := verifier.Authenticate(header)
+		if err != nil {
+			logrus.Debugf("bearer authentication failed: %v", err)
+			return nil, goerrors.New(http.StatusUnauthorized, "%s", err.Error())
+		}
+		return principal, nil
+	}
+	api.APIAuthorizer = authorizer{}
+}
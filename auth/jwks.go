@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jwksTimeout bounds the fetch of the keys of the JWKS URL
+	jwksTimeout = 10 * time.Second
+	// jwksRetryInterval throttles the fetches of the keys triggered by unknown key IDs
+	jwksRetryInterval = time.Minute
+)
+
+// jwk is a JSON Web Key, only the RSA signing keys are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type publicKey struct {
+	kid string
+	key *rsa.PublicKey
+}
+
+// keySet holds the RS256 public keys of a JWKS file, or of a JWKS URL fetched again every refresh
+// interval, and when a token is signed by a key it does not know yet, e.g. after a key rotation
+type keySet struct {
+	url       string
+	refresh   time.Duration
+	client    *http.Client
+	mu        sync.Mutex
+	keys      []publicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	fetching  bool
+}
+
+// newKeySet loads the keys of the JWKS file or URL, nil when none is configured. The URL may be
+// unavailable at startup, its keys are fetched again on use
+func newKeySet(cfg Config) (*keySet, error) {
+	if cfg.JWKSFile == "" && cfg.JWKSURL == "" {
+		return nil, nil
+	}
+
+	if cfg.JWKSFile != "" {
+		b, err := ioutil.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "newKeySet.ReadFile")
+		}
+		keys, err := parseJWKS(b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid JWKS file %s", cfg.JWKSFile)
+		}
+		return &keySet{keys: keys}, nil
+	}
+
+	s := &keySet{
+		url:     cfg.JWKSURL,
+		refresh: cfg.JWKSRefresh,
+		client:  &http.Client{Timeout: jwksTimeout},
+		triedAt: time.Now(),
+	}
+	keys, err := s.fetch()
+	if err != nil {
+		logrus.Warnf("JWKS %s not loaded: %v", s.url, err)
+		return s, nil
+	}
+	s.keys = keys
+	s.fetchedAt = s.triedAt
+	return s, nil
+}
+
+// lookup returns the keys with the key ID, all the keys when the token has no key ID
+func (s *keySet) lookup(kid string) []*rsa.PublicKey {
+	if s.url != "" && s.startFetch(kid) {
+		keys, err := s.fetch()
+		s.finishFetch(keys, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []*rsa.PublicKey{}
+	for _, key := range s.keys {
+		if kid == "" || key.kid == kid {
+			keys = append(keys, key.key)
+		}
+	}
+	return keys
+}
+
+// startFetch reports whether the keys are fetched again by this caller, because they are expired
+// or do not have the key ID. The other callers do not wait for the fetch, they use the current keys
+func (s *keySet) startFetch(kid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := s.refresh > 0 && time.Since(s.fetchedAt) > s.refresh
+	if s.fetching || !(expired || !s.knows(kid)) || time.Since(s.triedAt) <= jwksRetryInterval {
+		return false
+	}
+	s.fetching = true
+	s.triedAt = time.Now()
+	return true
+}
+
+// finishFetch replaces the keys with the keys fetched, the keys are kept when the fetch failed
+func (s *keySet) finishFetch(keys []publicKey, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetching = false
+	if err != nil {
+		logrus.Warnf("JWKS %s not refreshed: %v", s.url, err)
+		return
+	}
+	s.keys = keys
+	s.fetchedAt = s.triedAt
+}
+
+func (s *keySet) knows(kid string) bool {
+	for _, key := range s.keys {
+		if kid == "" || key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch returns the keys of the URL. It is called without the lock, a slow URL does not hold
+// up the verification of the tokens signed by the known keys
+func (s *keySet) fetch() ([]publicKey, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, errors.Wrap(err, "keySet.fetch")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("keySet.fetch: unexpected status %s", res.Status)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "keySet.fetch.ReadAll")
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "keySet.fetch")
+	}
+	return keys, nil
+}
+
+// parseJWKS returns the RSA signing keys of the JSON Web Key Set
+func parseJWKS(b []byte) ([]publicKey, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, errors.Wrap(err, "parseJWKS")
+	}
+
+	keys := []publicKey{}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != RS256) {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, errors.Errorf("parseJWKS: invalid modulus of key %q", key.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.Errorf("parseJWKS: invalid exponent of key %q", key.Kid)
+		}
+		keys = append(keys, publicKey{
+			kid: key.Kid,
+			key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("parseJWKS: no RSA signing key")
+	}
+	return keys, nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func jwksOf(t *testing.T, keys map[string]*rsa.PublicKey) []byte {
+	t.Helper()
+	set := map[string][]jwk{"keys": {}}
+	for kid, key := range keys {
+		set["keys"] = append(set["keys"], jwk{
+			Kty: "RSA",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestKeySetFetchWithoutLock(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the JWKS has the first key at startup, and after the rotation both keys once released
+	var mu sync.Mutex
+	fetches := 0
+	fetched := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches++
+		n := fetches
+		mu.Unlock()
+		if n == 1 {
+			w.Write(jwksOf(t, map[string]*rsa.PublicKey{"key-1": &first.PublicKey})) // nolint
+			return
+		}
+		fetched <- struct{}{}
+		<-release
+		w.Write(jwksOf(t, map[string]*rsa.PublicKey{"key-1": &first.PublicKey, "key-2": &second.PublicKey})) // nolint
+	}))
+	defer server.Close()
+
+	s, err := newKeySet(Config{JWKSURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.lookup("key-1")); got != 1 {
+		t.Fatalf("got %d keys at startup, want 1", got)
+	}
+
+	// an unknown key ID fetches the keys again once the retry interval has passed
+	s.mu.Lock()
+	s.triedAt = time.Now().Add(-2 * jwksRetryInterval)
+	s.mu.Unlock()
+	done := make(chan []*rsa.PublicKey, 1)
+	go func() {
+		done <- s.lookup("key-2")
+	}()
+	<-fetched
+
+	// the known keys are looked up during the fetch, and the fetch is not started twice
+	looked := make(chan int, 1)
+	go func() {
+		looked <- len(s.lookup("key-1")) + len(s.lookup("key-2"))
+	}()
+	select {
+	case got := <-looked:
+		if got != 1 {
+			t.Errorf("got %d keys during the fetch, want 1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup waited for the fetch")
+	}
+
+	close(release)
+	if got := len(<-done); got != 1 {
+		t.Errorf("got %d keys after the fetch, want 1", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fetches != 2 {
+		t.Errorf("got %d fetches, want 2", fetches)
+	}
+}
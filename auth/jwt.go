@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HS256 is the HMAC SHA-256 signing algorithm, verified with the shared secrets
+	HS256 = "HS256"
+	// RS256 is the RSA SHA-256 signing algorithm, verified with the public keys of the JWKS
+	RS256 = "RS256"
+
+	bearerPrefix = "bearer "
+)
+
+// Config configures the JWT verification. Secrets are the HS256 secrets, several during a
+// rotation, and JWKSFile or JWKSURL the JSON Web Key Set of the RS256 public keys, the keys of
+// the URL are fetched again every JWKSRefresh. Issuer and Audience are checked when set, Leeway
+// is the clock skew tolerated for exp, nbf and iat. The roles of the users are the known roles of
+// the RolesClaim, DefaultRole when it has none
+type Config struct {
+	Secrets     []string
+	JWKSFile    string
+	JWKSURL     string
+	JWKSRefresh time.Duration
+	Issuer      string
+	Audience    string
+	Leeway      time.Duration
+	RolesClaim  string
+	DefaultRole string
+}
+
+// Verifier verifies the signature and the claims of the JWT bearer tokens
+type Verifier struct {
+	secrets     [][]byte
+	keys        *keySet
+	issuer      string
+	audience    string
+	leeway      time.Duration
+	rolesClaim  string
+	defaultRole string
+	now         func() time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// NewVerifier creates the verifier of the tokens signed with the configured secrets or keys.
+// A verifier without any secret or key rejects every token
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.DefaultRole != "" && !KnownRole(cfg.DefaultRole) {
+		return nil, errors.Errorf("auth.NewVerifier: unknown default role %q", cfg.DefaultRole)
+	}
+
+	v := &Verifier{
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		leeway:      cfg.Leeway,
+		rolesClaim:  cfg.RolesClaim,
+		defaultRole: cfg.DefaultRole,
+		now:         time.Now,
+	}
+	for _, secret := range cfg.Secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			v.secrets = append(v.secrets, []byte(secret))
+		}
+	}
+
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "auth.NewVerifier")
+	}
+	v.keys = keys
+	return v, nil
+}
+
+// Configured reports whether the verifier has secrets or keys to verify tokens with
+func (v *Verifier) Configured() bool {
+	return len(v.secrets) != 0 || v.keys != nil
+}
+
+// Authenticate verifies the bearer token of an Authorization header, such as "Bearer eyJ..."
+func (v *Verifier) Authenticate(header string) (*models.Principal, error) {
+	header = strings.TrimSpace(header)
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "bearer token required")
+	}
+	return v.Verify(strings.TrimSpace(header[len(bearerPrefix):]))
+}
+
+// Verify checks the signature and the exp, nbf, iat, iss and aud claims of the token, and returns
+// the principal of its claims. Every failure is an errs.ErrUnauthorized
+func (v *Verifier) Verify(token string) (*models.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "malformed token")
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "malformed token signature")
+	}
+
+	// the algorithm is only trusted with the keys of its kind, so an RSA public key is never used as an HMAC secret
+	signed := []byte(parts[0] + "." + parts[1])
+	switch header.Alg {
+	case HS256:
+		err = v.verifyHMAC(signed, signature)
+	case RS256:
+		err = v.verifyRSA(header.Kid, signed, signature)
+	default:
+		err = errors.Wrapf(errs.ErrUnauthorized, "unsupported token algorithm %q", header.Alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "malformed token claims")
+	}
+	return v.principal(claims)
+}
+
+func (v *Verifier) verifyHMAC(signed, signature []byte) error {
+	for _, secret := range v.secrets {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed) // nolint
+		if hmac.Equal(mac.Sum(nil), signature) {
+			return nil
+		}
+	}
+	return errors.Wrap(errs.ErrUnauthorized, "invalid token signature")
+}
+
+func (v *Verifier) verifyRSA(kid string, signed, signature []byte) error {
+	if v.keys == nil {
+		return errors.Wrap(errs.ErrUnauthorized, "invalid token signature")
+	}
+
+	digest := sha256.Sum256(signed)
+	for _, key := range v.keys.lookup(kid) {
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	}
+	return errors.Wrap(errs.ErrUnauthorized, "invalid token signature")
+}
+
+// principal validates the registered claims and converts them to the principal
+func (v *Verifier) principal(claims map[string]interface{}) (*models.Principal, error) {
+	now := v.now()
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "token without expiry")
+	}
+	if now.After(exp.Add(v.leeway)) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.leeway).Before(nbf) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "token not valid yet")
+	}
+	if iat, ok := numericDate(claims["iat"]); ok && now.Add(v.leeway).Before(iat) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "token issued in the future")
+	}
+
+	issuer, _ := claims["iss"].(string)
+	if v.issuer != "" && issuer != v.issuer {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "unexpected token issuer")
+	}
+	if v.audience != "" && !contains(stringList(claims["aud"]), v.audience) {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "unexpected token audience")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.Wrap(errs.ErrUnauthorized, "token without subject")
+	}
+
+	// OAuth 2 scopes are a space separated scope claim, or an scp array
+	scopes := strings.Fields(stringValue(claims["scope"]))
+	if len(scopes) == 0 {
+		scopes = stringList(claims["scp"])
+	}
+
+	return &models.Principal{
+		Kind:      KindUser,
+		Subject:   subject,
+		Issuer:    issuer,
+		Scopes:    scopes,
+		Roles:     v.roles(claims),
+		ExpiresAt: strfmt.DateTime(exp.UTC()),
+		Claims:    claims,
+	}, nil
+}
+
+// roles returns the known roles of the roles claim, the default role when there is none
+func (v *Verifier) roles(claims map[string]interface{}) []string {
+	roles := []string{}
+	if v.rolesClaim != "" {
+		for _, role := range stringList(claims[v.rolesClaim]) {
+			if KnownRole(role) && !contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	if len(roles) == 0 && v.defaultRole != "" {
+		roles = append(roles, v.defaultRole)
+	}
+	return roles
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// numericDate converts a NumericDate claim, seconds since the epoch
+func numericDate(value interface{}) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// stringList returns a claim given as a string or an array of strings
+func stringList(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := []string{}
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				list = append(list, text)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func stringValue(value interface{}) string {
+	text, _ := value.(string)
+	return text
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+const testSecret = "test-secret"
+
+var testNow = time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// testKeys are the RSA keys of the tests, the first in the JWKS of the verifier and the second unknown to it
+type testKeys struct {
+	known   *rsa.PrivateKey
+	unknown *rsa.PrivateKey
+	jwks    string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	keys := &testKeys{}
+	for _, key := range []**rsa.PrivateKey{&keys.known, &keys.unknown} {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*key = k
+	}
+
+	set := map[string][]jwk{"keys": {{
+		Kty: "RSA",
+		Kid: "key-1",
+		Use: "sig",
+		Alg: RS256,
+		N:   base64.RawURLEncoding.EncodeToString(keys.known.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(keys.known.E)).Bytes()),
+	}}}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	keys.jwks = f.Name()
+	return keys
+}
+
+func newTestVerifier(t *testing.T, cfg Config) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+// sign builds a token of the header and the claims, signed by signer
+func sign(t *testing.T, header map[string]interface{}, claims map[string]interface{}, signer func(signed []byte) []byte) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := segment(header) + "." + segment(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signer([]byte(signed)))
+}
+
+func hmacSigner(secret []byte) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed) // nolint
+		return mac.Sum(nil)
+	}
+}
+
+func rsaSigner(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+}
+
+func noSignature([]byte) []byte {
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	defer os.Remove(keys.jwks)
+	v := newTestVerifier(t, Config{
+		Secrets:  []string{"old-secret", testSecret},
+		JWKSFile: keys.jwks,
+		Issuer:   "https://issuer.test",
+		Audience: "movies",
+		Leeway:   time.Minute,
+	})
+
+	// the public key of the JWKS in the forms an HMAC confusion attack would use as the secret
+	der, err := x509.MarshalPKIXPublicKey(&keys.known.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "https://issuer.test",
+			"aud":   "movies",
+			"iat":   testNow.Add(-time.Minute).Unix(),
+			"exp":   testNow.Add(time.Hour).Unix(),
+			"scope": "movies:read movies:write",
+		}
+		for name, value := range changes {
+			if value == nil {
+				delete(c, name)
+				continue
+			}
+			c[name] = value
+		}
+		return c
+	}
+	hs := map[string]interface{}{"alg": HS256, "typ": "JWT"}
+	rs := map[string]interface{}{"alg": RS256, "typ": "JWT", "kid": "key-1"}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "HS256", token: sign(t, hs, claims(nil), hmacSigner([]byte(testSecret)))},
+		{name: "HS256 with a rotated secret", token: sign(t, hs, claims(nil), hmacSigner([]byte("old-secret")))},
+		{name: "RS256", token: sign(t, rs, claims(nil), rsaSigner(t, keys.known))},
+		{name: "RS256 without kid", token: sign(t, map[string]interface{}{"alg": RS256}, claims(nil), rsaSigner(t, keys.known))},
+		{name: "audience list", token: sign(t, hs, claims(map[string]interface{}{"aud": []string{"other", "movies"}}), hmacSigner([]byte(testSecret)))},
+		{name: "expired within the leeway", token: sign(t, hs, claims(map[string]interface{}{"exp": testNow.Add(-30 * time.Second).Unix()}), hmacSigner([]byte(testSecret)))},
+
+		{name: "alg none", token: sign(t, map[string]interface{}{"alg": "none"}, claims(nil), noSignature), wantErr: "unsupported token algorithm"},
+		{name: "alg None", token: sign(t, map[string]interface{}{"alg": "None"}, claims(nil), noSignature), wantErr: "unsupported token algorithm"},
+		{name: "alg none signed", token: sign(t, map[string]interface{}{"alg": "none"}, claims(nil), hmacSigner([]byte(testSecret))), wantErr: "unsupported token algorithm"},
+		{name: "no alg", token: sign(t, map[string]interface{}{"typ": "JWT"}, claims(nil), hmacSigner([]byte(testSecret))), wantErr: "unsupported token algorithm"},
+		{name: "HS256 signed with the RSA public key PEM", token: sign(t, hs, claims(nil), hmacSigner(publicPEM)), wantErr: "invalid token signature"},
+		{name: "HS256 signed with the RSA public key DER", token: sign(t, hs, claims(nil), hmacSigner(der)), wantErr: "invalid token signature"},
+		{name: "HS256 signed with the RSA modulus", token: sign(t, map[string]interface{}{"alg": HS256, "kid": "key-1"}, claims(nil), hmacSigner(keys.known.N.Bytes())), wantErr: "invalid token signature"},
+		{name: "RS256 with an HMAC signature", token: sign(t, rs, claims(nil), hmacSigner([]byte(testSecret))), wantErr: "invalid token signature"},
+		{name: "wrong secret", token: sign(t, hs, claims(nil), hmacSigner([]byte("other-secret"))), wantErr: "invalid token signature"},
+		{name: "unknown kid", token: sign(t, map[string]interface{}{"alg": RS256, "kid": "key-2"}, claims(nil), rsaSigner(t, keys.known)), wantErr: "invalid token signature"},
+		{name: "unknown key", token: sign(t, rs, claims(nil), rsaSigner(t, keys.unknown)), wantErr: "invalid token signature"},
+		{name: "expired", token: sign(t, hs, claims(map[string]interface{}{"exp": testNow.Add(-2 * time.Minute).Unix()}), hmacSigner([]byte(testSecret))), wantErr: "token expired"},
+		{name: "without expiry", token: sign(t, hs, claims(map[string]interface{}{"exp": nil}), hmacSigner([]byte(testSecret))), wantErr: "token without expiry"},
+		{name: "not valid yet", token: sign(t, hs, claims(map[string]interface{}{"nbf": testNow.Add(2 * time.Minute).Unix()}), hmacSigner([]byte(testSecret))), wantErr: "token not valid yet"},
+		{name: "issued in the future", token: sign(t, hs, claims(map[string]interface{}{"iat": testNow.Add(2 * time.Minute).Unix()}), hmacSigner([]byte(testSecret))), wantErr: "token issued in the future"},
+		{name: "wrong issuer", token: sign(t, hs, claims(map[string]interface{}{"iss": "https://other.test"}), hmacSigner([]byte(testSecret))), wantErr: "unexpected token issuer"},
+		{name: "wrong audience", token: sign(t, hs, claims(map[string]interface{}{"aud": "billing"}), hmacSigner([]byte(testSecret))), wantErr: "unexpected token audience"},
+		{name: "wrong audience list", token: sign(t, hs, claims(map[string]interface{}{"aud": []string{"billing", "Movies"}}), hmacSigner([]byte(testSecret))), wantErr: "unexpected token audience"},
+		{name: "without audience", token: sign(t, hs, claims(map[string]interface{}{"aud": nil}), hmacSigner([]byte(testSecret))), wantErr: "unexpected token audience"},
+		{name: "without subject", token: sign(t, hs, claims(map[string]interface{}{"sub": nil}), hmacSigner([]byte(testSecret))), wantErr: "token without subject"},
+		{name: "two segments", token: "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9", wantErr: "malformed token"},
+		{name: "malformed header", token: "not-json.eyJzdWIiOiJhbGljZSJ9.", wantErr: "malformed token header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := v.Verify(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if principal.Subject != "alice" || principal.Kind != KindUser {
+					t.Errorf("got principal %+v", principal)
+				}
+				if !reflect.DeepEqual(principal.Scopes, []string{"movies:read", "movies:write"}) {
+					t.Errorf("got scopes %v", principal.Scopes)
+				}
+				return
+			}
+			if errors.Cause(err) != errs.ErrUnauthorized {
+				t.Fatalf("got %v, %v, want errs.ErrUnauthorized", principal, err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestVerifyAlgorithmConfusion checks the public keys of a verifier without secrets are not used as HMAC secrets
+func TestVerifyAlgorithmConfusion(t *testing.T) {
+	keys := newTestKeys(t)
+	defer os.Remove(keys.jwks)
+	v := newTestVerifier(t, Config{JWKSFile: keys.jwks})
+
+	der, err := x509.MarshalPKIXPublicKey(&keys.known.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{"sub": "alice", "exp": testNow.Add(time.Hour).Unix()}
+	secrets := map[string][]byte{
+		"PEM":     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		"DER":     der,
+		"modulus": keys.known.N.Bytes(),
+		"empty":   {},
+	}
+	for name, secret := range secrets {
+		token := sign(t, map[string]interface{}{"alg": HS256, "kid": "key-1"}, claims, hmacSigner(secret))
+		if _, err := v.Verify(token); errors.Cause(err) != errs.ErrUnauthorized {
+			t.Errorf("HS256 signed with the %s public key: got %v, want errs.ErrUnauthorized", name, err)
+		}
+	}
+
+	// the same claims signed with the private key are accepted
+	if _, err := v.Verify(sign(t, map[string]interface{}{"alg": RS256, "kid": "key-1"}, claims, rsaSigner(t, keys.known))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	v := newTestVerifier(t, Config{Secrets: []string{testSecret}})
+	token := sign(t, map[string]interface{}{"alg": HS256}, map[string]interface{}{"sub": "alice", "exp": testNow.Add(time.Hour).Unix()}, hmacSigner([]byte(testSecret)))
+
+	tests := []struct {
+		header  string
+		wantErr bool
+	}{
+		{header: "Bearer " + token},
+		{header: "  bearer   " + token + " "},
+		{header: "Basic " + token, wantErr: true},
+		{header: token, wantErr: true},
+		{header: "Bearer", wantErr: true},
+		{header: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := v.Authenticate(tt.header)
+		if tt.wantErr {
+			if errors.Cause(err) != errs.ErrUnauthorized {
+				t.Errorf("Authenticate(%q): got %v, want errs.ErrUnauthorized", tt.header, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Authenticate(%q): %v", tt.header, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RoleViewer reads the resources which are not public, such as the jobs
+	RoleViewer = "viewer"
+	// RoleEditor changes the movies and genres, and reads like RoleViewer
+	RoleEditor = "editor"
+	// RoleAdmin performs every action, including the administration ones
+	RoleAdmin = "admin"
+)
+
+// Roles are the roles, from the least to the most privileged. A role grants the actions of the
+// roles before it
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// Action is an action of the services checked by the policy
+type Action string
+
+const (
+	// ReadJobs reads the jobs of the long running operations
+	ReadJobs Action = "jobs.read"
+	// CancelJobs cancels the jobs
+	CancelJobs Action = "jobs.cancel"
+	// EditMovies creates, changes, deletes and restores the movies, imports and bulk edits them
+	EditMovies Action = "movies.edit"
+	// AdministerMovies purges and re-enriches the movies, and reads the soft deleted ones
+	AdministerMovies Action = "movies.administer"
+	// EditGenres creates, changes and merges the genres
+	EditGenres Action = "genres.edit"
+	// AdministerMetadataCache reads the statistics of the metadata cache and purges it
+	AdministerMetadataCache Action = "metadata-cache.administer"
+	// AdministerAPIKeys creates, lists, rotates and revokes the API keys
+	AdministerAPIKeys Action = "api-keys.administer"
+	// ManageWatchlist lists, adds and removes the movies of the own watchlist
+	ManageWatchlist Action = "watchlist.manage"
+	// ReviewMovies posts and edits the own reviews of the movies
+	ReviewMovies Action = "reviews.write"
+)
+
+// policy is the least privileged role allowed to perform each action. The reads of the movies
+// and genres are public and not part of the policy, except for the soft deleted movies
+var policy = map[Action]string{
+	ReadJobs:                RoleViewer,
+	CancelJobs:              RoleEditor,
+	EditMovies:              RoleEditor,
+	AdministerMovies:        RoleAdmin,
+	EditGenres:              RoleEditor,
+	AdministerMetadataCache: RoleAdmin,
+	AdministerAPIKeys:       RoleAdmin,
+	ManageWatchlist:         RoleViewer,
+	ReviewMovies:            RoleViewer,
+}
+
+// Authorize checks the action against the roles of the principal of the context. It returns
+// errs.ErrUnauthorized for anonymous callers and errs.ErrForbidden when no role of the principal
+// allows the action. The services call it before acting, so every transport gets the same rules
+func Authorize(ctx context.Context, action Action) error {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return errors.Wrapf(errs.ErrUnauthorized, "authentication required to %s", action)
+	}
+
+	required, ok := policy[action]
+	if !ok {
+		return errors.Wrapf(errs.ErrForbidden, "unknown action %s", action)
+	}
+	if rank(principal) < roleRank(required) {
+		return errors.Wrapf(errs.ErrForbidden, "role %s required to %s", required, action)
+	}
+	return nil
+}
+
+// UserSubject authorizes the action and returns the subject of the user of the context, for the
+// resources belonging to users such as the watchlists and the reviews. The API keys are service
+// clients, which are rejected with errs.ErrForbidden
+func UserSubject(ctx context.Context, action Action) (string, error) {
+	if err := Authorize(ctx, action); err != nil {
+		return "", err
+	}
+	principal, _ := FromContext(ctx)
+	if principal.Kind != KindUser {
+		return "", errors.Wrapf(errs.ErrForbidden, "only users may %s", action)
+	}
+	return principal.Subject, nil
+}
+
+// HasRole reports whether the principal of the context has the role or a more privileged one
+func HasRole(ctx context.Context, role string) bool {
+	principal, ok := FromContext(ctx)
+	return ok && rank(principal) >= roleRank(role)
+}
+
+// RolesOfScopes returns the role of the API keys with the scopes, so an API key is allowed the
+// actions of the operations its scopes grant
+func RolesOfScopes(scopes []string) []string {
+	switch {
+	case Grants(scopes, ScopeAdmin):
+		return []string{RoleAdmin}
+	case Grants(scopes, ScopeMoviesWrite):
+		return []string{RoleEditor}
+	case Grants(scopes, ScopeMoviesRead):
+		return []string{RoleViewer}
+	}
+	return []string{}
+}
+
+// KnownRole reports whether the role is one of Roles
+func KnownRole(role string) bool {
+	return roleRank(role) > 0
+}
+
+// rank returns the rank of the most privileged role of the principal, 0 without any known role
+func rank(principal *models.Principal) int {
+	best := 0
+	for _, role := range principal.Roles {
+		if r := roleRank(role); r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+// roleRank returns the 1 based rank of the role in Roles, 0 when unknown
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+func TestAuthorize(t *testing.T) {
+	user := func(roles ...string) *models.Principal {
+		return &models.Principal{Kind: KindUser, Subject: "alice", Roles: roles}
+	}
+
+	tests := []struct {
+		name      string
+		principal *models.Principal
+		action    Action
+		wantErr   error
+	}{
+		{name: "anonymous", action: ReadJobs, wantErr: errs.ErrUnauthorized},
+		{name: "anonymous unknown action", action: Action("movies.fly"), wantErr: errs.ErrUnauthorized},
+		{name: "without role", principal: user(), action: ReadJobs, wantErr: errs.ErrForbidden},
+		{name: "unknown role", principal: user("owner"), action: ReadJobs, wantErr: errs.ErrForbidden},
+		{name: "viewer reads the jobs", principal: user(RoleViewer), action: ReadJobs},
+		{name: "viewer does not edit", principal: user(RoleViewer), action: EditMovies, wantErr: errs.ErrForbidden},
+		{name: "editor edits", principal: user(RoleEditor), action: EditMovies},
+		{name: "editor reads like a viewer", principal: user(RoleEditor), action: ReadJobs},
+		{name: "editor does not administer", principal: user(RoleEditor), action: AdministerMovies, wantErr: errs.ErrForbidden},
+		{name: "admin administers", principal: user(RoleAdmin), action: AdministerMovies},
+		{name: "admin edits", principal: user(RoleAdmin), action: EditGenres},
+		{name: "most privileged role", principal: user(RoleViewer, RoleAdmin), action: AdministerAPIKeys},
+		{name: "unknown action", principal: user(RoleAdmin), action: Action("movies.fly"), wantErr: errs.ErrForbidden},
+		{name: "API key role", principal: &models.Principal{Kind: KindAPIKey, Subject: "key", Roles: RolesOfScopes([]string{ScopeMoviesWrite})}, action: EditMovies},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(NewContext(context.Background(), tt.principal), tt.action)
+			if errors.Cause(err) != tt.wantErr {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRolesOfScopes(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		want   []string
+	}{
+		{scopes: nil, want: []string{}},
+		{scopes: []string{"other"}, want: []string{}},
+		{scopes: []string{ScopeMoviesRead}, want: []string{RoleViewer}},
+		{scopes: []string{ScopeMoviesWrite}, want: []string{RoleEditor}},
+		{scopes: []string{ScopeMoviesRead, ScopeMoviesWrite}, want: []string{RoleEditor}},
+		{scopes: []string{ScopeAdmin}, want: []string{RoleAdmin}},
+		{scopes: []string{ScopeMoviesRead, ScopeAdmin}, want: []string{RoleAdmin}},
+	}
+	for _, tt := range tests {
+		if got := RolesOfScopes(tt.scopes); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RolesOfScopes(%v) = %v, want %v", tt.scopes, got, tt.want)
+		}
+	}
+}
+
+func TestUserSubject(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *models.Principal
+		wantErr   error
+	}{
+		{name: "anonymous", wantErr: errs.ErrUnauthorized},
+		{name: "user", principal: &models.Principal{Kind: KindUser, Subject: "alice", Roles: []string{RoleViewer}}},
+		{name: "user without role", principal: &models.Principal{Kind: KindUser, Subject: "alice"}, wantErr: errs.ErrForbidden},
+		{name: "API key", principal: &models.Principal{Kind: KindAPIKey, Subject: "key", Roles: []string{RoleAdmin}}, wantErr: errs.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := UserSubject(NewContext(context.Background(), tt.principal), ManageWatchlist)
+			if errors.Cause(err) != tt.wantErr {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && subject != "alice" {
+				t.Errorf("got subject %q, want alice", subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+
+	goerrors "github.com/go-openapi/errors"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/gen/models"
+)
+
+const (
+	// KindUser is the kind of the principals of the bearer tokens
+	KindUser = "user"
+	// KindAPIKey is the kind of the principals of the API keys
+	KindAPIKey = "api-key"
+
+	// ScopeMoviesRead reads the resources which are not public, such as the jobs
+	ScopeMoviesRead = "movies:read"
+	// ScopeMoviesWrite changes the movies and genres, and reads like ScopeMoviesRead
+	ScopeMoviesWrite = "movies:write"
+	// ScopeAdmin grants every operation
+	ScopeAdmin = "admin"
+
+	// requiredScopeExtension names the scope an operation requires, in the swagger specification
+	requiredScopeExtension = "x-required-scope"
+)
+
+// Scopes are the scopes an API key may have
+var Scopes = []string{ScopeMoviesRead, ScopeMoviesWrite, ScopeAdmin}
+
+// Grants reports whether the scopes grant the required scope
+func Grants(scopes []string, required string) bool {
+	for _, scope := range scopes {
+		switch {
+		case scope == required, scope == ScopeAdmin:
+			return true
+		case scope == ScopeMoviesWrite && required == ScopeMoviesRead:
+			return true
+		}
+	}
+	return false
+}
+
+// authorizer checks the scopes of the API keys against the x-required-scope of the operations.
+// The users of the bearer tokens are not limited by scopes. The denials are go-openapi errors, so
+// the runtime renders them as 403
+type authorizer struct{}
+
+func (authorizer) Authorize(r *http.Request, principal interface{}) error {
+	p, ok := principal.(*models.Principal)
+	if !ok || p.Kind != KindAPIKey {
+		return nil
+	}
+
+	// an operation without a required scope is not available to the API keys
+	required := ""
+	if route := middleware.MatchedRouteFrom(r); route != nil && route.Operation != nil {
+		required, _ = route.Operation.Extensions.GetString(requiredScopeExtension)
+	}
+	if required == "" {
+		return goerrors.New(http.StatusForbidden, "operation not available to API keys")
+	}
+	if !Grants(p.Scopes, required) {
+		return goerrors.New(http.StatusForbidden, "API key without the %s scope", required)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	log "github.com/movieManagement/logging"
+	"github.com/movieManagement/migration"
+	"github.com/pkg/errors"
+)
+
+const migrateUsage = "usage: movie-service migrate up|down [steps]|status"
+
+// Migrate is the migrate subcommand entry point. up applies the pending migrations, down
+// reverts the last (or the last steps) applied migrations and status lists the migrations
+func Migrate(migrator migration.Migrator, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Infof("Applied %d migrations", len(applied))
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return errors.Errorf("invalid steps %q, %s", args[1], migrateUsage)
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Infof("Reverted %d migrations", len(reverted))
+		return nil
+	case "status":
+		return printStatus(ctx, migrator)
+	default:
+		return errors.Errorf("unknown migrate command %q, %s", args[0], migrateUsage)
+	}
+}
+
+func printStatus(ctx context.Context, migrator migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Unknown {
+			appliedAt += " (unknown to this build)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
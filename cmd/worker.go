@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/movieManagement/jobs"
+	log "github.com/movieManagement/logging"
+)
+
+// Work is the worker subcommand entry point, running the jobs until interrupted or terminated
+func Work(worker *jobs.Worker) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Infof("Received %s, stopping the job workers...", sig)
+		cancel()
+	}()
+
+	worker.Run(ctx)
+	return nil
+}
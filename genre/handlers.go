@@ -0,0 +1,45 @@
+package genre
+
+import (
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/genre"
+	"github.com/movieManagement/swagger"
+)
+
+// Configure configures the genre taxonomy service
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.GenreListGenresHandler = genre.ListGenresHandlerFunc(func(params genre.ListGenresParams) middleware.Responder {
+		result, err := service.ListGenres(params.HTTPRequest.Context(), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ListGenres :: ", err)
+		}
+		return genre.NewListGenresOK().WithPayload(result)
+	})
+
+	api.GenreCreateGenreHandler = genre.CreateGenreHandlerFunc(func(params genre.CreateGenreParams, principal *models.Principal) middleware.Responder {
+		result, err := service.CreateGenre(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("CreateGenre :: ", err)
+		}
+		return genre.NewCreateGenreCreated().WithPayload(result)
+	})
+
+	api.GenreUpdateGenreHandler = genre.UpdateGenreHandlerFunc(func(params genre.UpdateGenreParams, principal *models.Principal) middleware.Responder {
+		result, err := service.UpdateGenre(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("UpdateGenre :: ", err)
+		}
+		return genre.NewUpdateGenreOK().WithPayload(result)
+	})
+
+	api.GenreMergeGenreHandler = genre.MergeGenreHandlerFunc(func(params genre.MergeGenreParams, principal *models.Principal) middleware.Responder {
+		result, err := service.MergeGenre(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("MergeGenre :: ", err)
+		}
+		return genre.NewMergeGenreOK().WithPayload(result)
+	})
+}
@@ -0,0 +1,40 @@
+package genre
+
+import (
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+	"github.com/movieManagement/gen/models"
+)
+
+// SQLGenres . . .
+type SQLGenres struct {
+	ID         sql.NullInt64  `json:"ID,omitempty"`
+	Name       sql.NullString `json:"Name,omitempty"`
+	Aliases    pq.StringArray `json:"Aliases,omitempty"`
+	MovieCount sql.NullInt64  `json:"MovieCount,omitempty"`
+}
+
+func (sql *SQLGenres) toGenre() *models.Genre {
+	genre := models.Genre{
+		ID:         sql.ID.Int64,
+		Name:       sql.Name.String,
+		Aliases:    []string(sql.Aliases),
+		MovieCount: sql.MovieCount.Int64,
+	}
+	return &genre
+}
+
+// AliasKey returns the normalized form of a genre name or alias, so that spelling
+// variants such as "Sci-Fi", "SciFi" and "sci fi" resolve to the same genre
+func AliasKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,322 @@
+package genre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// GenreTable . . .
+	GenreTable = "public.genres as g"
+	// AliasTable . . .
+	AliasTable = "public.genre_aliases"
+
+	// pqUniqueViolation is the postgres error code of a unique constraint violation
+	pqUniqueViolation = "23505"
+)
+
+var genreReturnFields = []string{
+	"g.id as ID",
+	"g.name as Name",
+	"ARRAY(SELECT ga.alias FROM public.genre_aliases ga WHERE ga.genre_id = g.id AND ga.alias <> g.name ORDER BY ga.alias) as Aliases",
+	"(SELECT count(*) FROM public.movie_genres mg WHERE mg.genre_id = g.id) as MovieCount",
+}
+
+// Repository interface includes a list of supported repository operations
+type Repository interface {
+	ListGenres(ctx context.Context) ([]*models.Genre, error)
+	CreateGenre(ctx context.Context, name string, aliases []string) (*models.Genre, error)
+	UpdateGenre(ctx context.Context, id int64, name string, aliases []string) (*models.Genre, error)
+	MergeGenre(ctx context.Context, sourceID, targetID int64) (*models.Genre, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// Resolve returns the id of the canonical genre the specified name is an alias of. Unknown
+// names are created as new genres, with the name as their first alias
+func Resolve(tx *sqlz.Tx, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	key := AliasKey(name)
+	if key == "" {
+		return 0, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("invalid genre name %q", name))
+	}
+
+	var id int64
+	err := tx.Select("genre_id").
+		From(AliasTable).
+		Where(sqlz.Eq("alias_key", key)).
+		GetRow(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, errors.Wrap(err, "Resolve.SelectAlias")
+	}
+
+	_, err = tx.InsertInto("public.genres").
+		Columns("name").
+		Values(name).
+		OnConflictDoNothing().
+		Exec()
+	if err != nil {
+		return 0, errors.Wrap(err, "Resolve.InsertGenre")
+	}
+
+	err = tx.Select("id").
+		From("public.genres").
+		Where(sqlz.SQLCond("lower(name) = lower(?)", name)).
+		GetRow(&id)
+	if err != nil {
+		return 0, errors.Wrap(err, "Resolve.SelectGenre")
+	}
+
+	err = addAliases(tx, id, []string{name})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// addAliases attaches the aliases to the genre. An alias already attached to another genre is a conflict
+func addAliases(tx *sqlz.Tx, id int64, aliases []string) error {
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		key := AliasKey(alias)
+		if key == "" {
+			return errors.Wrap(errs.ErrInvalid, fmt.Sprintf("invalid genre alias %q", alias))
+		}
+
+		var owner int64
+		err := tx.Select("genre_id").
+			From(AliasTable).
+			Where(sqlz.Eq("alias_key", key)).
+			GetRow(&owner)
+		if err == nil {
+			if owner != id {
+				return errors.Wrap(errs.ErrConflict, fmt.Sprintf("alias %q belongs to genre %d", alias, owner))
+			}
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return errors.Wrap(err, "addAliases.SelectQuery")
+		}
+
+		_, err = tx.InsertInto(AliasTable).
+			Columns("alias_key", "alias", "genre_id").
+			Values(key, alias, id).
+			Exec()
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "addAliases.InsertQuery")
+		}
+	}
+	return nil
+}
+
+// conflictOrErr maps unique constraint violations to errs.ErrConflict
+func conflictOrErr(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation {
+		return errs.ErrConflict
+	}
+	return err
+}
+
+// ListGenres returns all the canonical genres, sorted by name
+func (repo *repository) ListGenres(ctx context.Context) ([]*models.Genre, error) {
+	logrus.Debugf("ListGenres repo")
+	sqlGenres := []SQLGenres{}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Select(genreReturnFields...).
+		From(GenreTable).
+		OrderBy(sqlz.Asc("g.name")).
+		GetAll(&sqlGenres)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "ListGenres.SelectQuery")
+	}
+
+	genres := make([]*models.Genre, 0, len(sqlGenres))
+	for _, sqlGenre := range sqlGenres {
+		genres = append(genres, sqlGenre.toGenre())
+	}
+	return genres, nil
+}
+
+// CreateGenre creates a canonical genre with its aliases
+func (repo *repository) CreateGenre(ctx context.Context, name string, aliases []string) (*models.Genre, error) {
+	logrus.Debugf("CreateGenre repo")
+	sqlGenres := SQLGenres{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		var id int64
+		err := tx.InsertInto("public.genres").
+			Columns("name").
+			Values(strings.TrimSpace(name)).
+			Returning("id").
+			GetRow(&id)
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "InsertQuery")
+		}
+
+		err = addAliases(tx, id, append([]string{name}, aliases...))
+		if err != nil {
+			return err
+		}
+
+		return getGenre(tx, id, &sqlGenres)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "CreateGenre")
+	}
+
+	return sqlGenres.toGenre(), nil
+}
+
+// UpdateGenre renames the genre and replaces its aliases. The current name is kept as an
+// alias so the data still using it resolves to the renamed genre
+func (repo *repository) UpdateGenre(ctx context.Context, id int64, name string, aliases []string) (*models.Genre, error) {
+	logrus.Debugf("UpdateGenre repo")
+	sqlGenres := SQLGenres{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		current := SQLGenres{}
+		err := tx.Select("g.id as ID", "g.name as Name").
+			From(GenreTable).
+			Where(sqlz.Eq("g.id", id)).
+			Lock(sqlz.ForUpdate()).
+			GetRow(&current)
+		if err == sql.ErrNoRows {
+			return errs.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		_, err = tx.Update("public.genres").
+			Set("name", strings.TrimSpace(name)).
+			Where(sqlz.Eq("id", id)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "UpdateQuery")
+		}
+
+		keep := append([]string{name, current.Name.String}, aliases...)
+		keys := make([]interface{}, 0, len(keep))
+		for _, alias := range keep {
+			keys = append(keys, AliasKey(alias))
+		}
+		_, err = tx.DeleteFrom(AliasTable).
+			Where(sqlz.Eq("genre_id", id), sqlz.NotIn("alias_key", keys...)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "DeleteAliasQuery")
+		}
+
+		err = addAliases(tx, id, keep)
+		if err != nil {
+			return err
+		}
+
+		return getGenre(tx, id, &sqlGenres)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "UpdateGenre")
+	}
+
+	return sqlGenres.toGenre(), nil
+}
+
+// MergeGenre moves the movies and aliases of the source genre to the target genre, and
+// deletes the source genre. Its name becomes an alias of the target genre
+func (repo *repository) MergeGenre(ctx context.Context, sourceID, targetID int64) (*models.Genre, error) {
+	logrus.Debugf("MergeGenre repo")
+	sqlGenres := SQLGenres{}
+
+	if sourceID == targetID {
+		return nil, errors.Wrap(errs.ErrInvalid, "MergeGenre: a genre can not be merged into itself")
+	}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		var locked []int64
+		err := tx.Select("g.id").
+			From(GenreTable).
+			Where(sqlz.In("g.id", sourceID, targetID)).
+			OrderBy(sqlz.Asc("g.id")).
+			Lock(sqlz.ForUpdate()).
+			GetAll(&locked)
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+		if len(locked) != 2 {
+			return errs.ErrNotFound
+		}
+
+		_, err = tx.Exec(`INSERT INTO public.movie_genres (movie_id, genre_id)
+			SELECT movie_id, $1 FROM public.movie_genres WHERE genre_id = $2
+			ON CONFLICT DO NOTHING`, targetID, sourceID)
+		if err != nil {
+			return errors.Wrap(err, "InsertMovieGenresQuery")
+		}
+
+		_, err = tx.Update(AliasTable).
+			Set("genre_id", targetID).
+			Where(sqlz.Eq("genre_id", sourceID)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "UpdateAliasQuery")
+		}
+
+		_, err = tx.DeleteFrom("public.genres").
+			Where(sqlz.Eq("id", sourceID)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "DeleteQuery")
+		}
+
+		return getGenre(tx, targetID, &sqlGenres)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "MergeGenre")
+	}
+
+	return sqlGenres.toGenre(), nil
+}
+
+func getGenre(tx *sqlz.Tx, id int64, into *SQLGenres) error {
+	err := tx.Select(genreReturnFields...).
+		From(GenreTable).
+		Where(sqlz.Eq("g.id", id)).
+		GetRow(into)
+	if err == sql.ErrNoRows {
+		return errs.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package genre
+
+import (
+	"context"
+
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/genre"
+	"github.com/pkg/errors"
+)
+
+// Service interface is a list of services for the genre taxonomy
+type Service interface {
+	ListGenres(ctx context.Context, in *genre.ListGenresParams) (*models.GenreList, error)
+	CreateGenre(ctx context.Context, in *genre.CreateGenreParams) (*models.Genre, error)
+	UpdateGenre(ctx context.Context, in *genre.UpdateGenreParams) (*models.Genre, error)
+	MergeGenre(ctx context.Context, in *genre.MergeGenreParams) (*models.Genre, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// New is a simple helper function to create a service instance
+func New(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// ListGenres service definition
+func (s *service) ListGenres(ctx context.Context, in *genre.ListGenresParams) (*models.GenreList, error) {
+	log.Debugf("entered service ListGenres")
+	genres, err := s.repo.ListGenres(ctx)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ListGenres")
+	}
+	return &models.GenreList{Data: genres}, nil
+}
+
+// CreateGenre service definition
+func (s *service) CreateGenre(ctx context.Context, in *genre.CreateGenreParams) (*models.Genre, error) {
+	log.Debugf("entered service CreateGenre")
+	if err := auth.Authorize(ctx, auth.EditGenres); err != nil {
+		return nil, errors.Wrap(err, "service.CreateGenre")
+	}
+	genre, err := s.repo.CreateGenre(ctx, *in.Genre.Name, in.Genre.Aliases)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.CreateGenre")
+	}
+	return genre, nil
+}
+
+// UpdateGenre service definition
+func (s *service) UpdateGenre(ctx context.Context, in *genre.UpdateGenreParams) (*models.Genre, error) {
+	log.Debugf("entered service UpdateGenre")
+	if err := auth.Authorize(ctx, auth.EditGenres); err != nil {
+		return nil, errors.Wrap(err, "service.UpdateGenre")
+	}
+	genre, err := s.repo.UpdateGenre(ctx, in.ID, *in.Genre.Name, in.Genre.Aliases)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.UpdateGenre")
+	}
+	return genre, nil
+}
+
+// MergeGenre service definition
+func (s *service) MergeGenre(ctx context.Context, in *genre.MergeGenreParams) (*models.Genre, error) {
+	log.Debugf("entered service MergeGenre")
+	if err := auth.Authorize(ctx, auth.EditGenres); err != nil {
+		return nil, errors.Wrap(err, "service.MergeGenre")
+	}
+	genre, err := s.repo.MergeGenre(ctx, in.ID, *in.Merge.TargetID)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.MergeGenre")
+	}
+	return genre, nil
+}
@@ -2,11 +2,14 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/movieManagement/gen/models"
 	"github.com/movieManagement/gen/restapi/operations/health"
+	"github.com/movieManagement/provider"
 )
 
 // Service handles async log of audit event
@@ -18,14 +21,17 @@ type service struct {
 	hcdb       *sqlx.DB
 	gitHash    string
 	buildStamp string
+	metadata   provider.MetadataProvider
 }
 
-// New is a simple helper function to create a service instance
-func New(hcdb *sqlx.DB, GitHash, BuildStamp string) Service {
+// New is a simple helper function to create a service instance. The metadata providers are
+// reported degraded while their circuit breakers are open
+func New(hcdb *sqlx.DB, GitHash, BuildStamp string, metadata provider.MetadataProvider) Service {
 	return &service{
 		hcdb:       hcdb,
 		gitHash:    GitHash,
 		buildStamp: BuildStamp,
+		metadata:   metadata,
 	}
 }
 
@@ -52,5 +58,35 @@ func (s service) HealthCheck(ctx context.Context, in *health.GetHealthParams) (*
 		Githash:        s.gitHash,
 		BuildTimeStamp: s.buildStamp,
 		Healths:        []*models.HealthStatus{&hs}}
+
+	for _, status := range providerStatuses(s.metadata) {
+		response.Healths = append(response.Healths, status)
+		if !status.Healthy {
+			response.Status = "degraded"
+		}
+	}
 	return &response, nil
 }
+
+// providerStatuses reports the metadata providers, which are unhealthy while their circuit breaker is open
+func providerStatuses(metadata provider.MetadataProvider) []*models.HealthStatus {
+	if metadata == nil {
+		return nil
+	}
+
+	statuses := []*models.HealthStatus{}
+	for _, status := range provider.Statuses(metadata) {
+		hs := models.HealthStatus{
+			TimeStamp: time.Now().String(),
+			Healthy:   !status.Degraded(),
+			Name:      "PROVIDER_" + strings.ToUpper(status.Provider),
+			Duration:  "0ms",
+		}
+		if status.Degraded() {
+			hs.Error = fmt.Sprintf("circuit %s after %d consecutive failures, retried in %s",
+				status.State, status.Failures, time.Until(status.OpenUntil).Round(time.Second))
+		}
+		statuses = append(statuses, &hs)
+	}
+	return statuses
+}
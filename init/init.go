@@ -3,14 +3,12 @@ package init
 import (
 	"fmt"
 
-	imdb "github.com/eefret/go-imdb"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
 var (
 	stage string
-	im    *imdb.OmdbApi
 )
 
 // CommonInit initializes the common properties
@@ -45,13 +43,3 @@ func Init() {
 func GetStage() string {
 	return stage
 }
-
-// ImdbVariable loads all the SSM values based on stage.
-func ImdbVariable() {
-	im = imdb.Init("my-api-key")
-}
-
-// GetImdbInit . . .
-func GetImdbInit() *imdb.OmdbApi {
-	return im
-}
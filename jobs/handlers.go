@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/job"
+	"github.com/movieManagement/swagger"
+)
+
+// Configure configures the job service
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.JobGetJobHandler = job.GetJobHandlerFunc(func(params job.GetJobParams, principal *models.Principal) middleware.Responder {
+		result, err := service.GetJob(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("GetJob :: ", err)
+		}
+		return job.NewGetJobOK().WithPayload(result)
+	})
+
+	api.JobCancelJobHandler = job.CancelJobHandlerFunc(func(params job.CancelJobParams, principal *models.Principal) middleware.Responder {
+		result, err := service.CancelJob(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("CancelJob :: ", err)
+		}
+		return job.NewCancelJobAccepted().WithPayload(result)
+	})
+}
+
+// Location returns the URL of the resource of the job, for the Location header of the 202 responses
+func Location(id int64) string {
+	return fmt.Sprintf("/v1/jobs/%d", id)
+}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+// The job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+	StatusCanceled  = "canceled"
+)
+
+// maxErrors is the number of item errors kept per job
+const maxErrors = 100
+
+// Job is a unit of background work of a kind, with the JSON payload of its handler
+type Job struct {
+	ID              int64
+	Kind            string
+	Payload         types.JSONText
+	Status          string
+	Attempts        int
+	MaxAttempts     int
+	DedupeKey       sql.NullString
+	RunAt           time.Time
+	LockedUntil     pq.NullTime
+	LastError       sql.NullString
+	ProgressTotal   int64
+	ProgressDone    int64
+	ProgressFailed  int64
+	Checkpoint      sql.NullString
+	Errors          pq.StringArray
+	CancelRequested bool
+	CreatedAt       time.Time
+	StartedAt       pq.NullTime
+	FinishedAt      pq.NullTime
+	UpdatedAt       time.Time
+}
+
+// ToJob converts the job to its API model
+func (job *Job) ToJob() *models.Job {
+	result := models.Job{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Status:      job.Status,
+		Attempts:    int64(job.Attempts),
+		MaxAttempts: int64(job.MaxAttempts),
+		Progress: &models.JobProgress{
+			Total:  job.ProgressTotal,
+			Done:   job.ProgressDone,
+			Failed: job.ProgressFailed,
+		},
+		Errors:          []string(job.Errors),
+		LastError:       job.LastError.String,
+		CancelRequested: job.CancelRequested,
+		CreatedAt:       strfmt.DateTime(job.CreatedAt),
+		UpdatedAt:       strfmt.DateTime(job.UpdatedAt),
+	}
+	if job.StartedAt.Valid {
+		startedAt := strfmt.DateTime(job.StartedAt.Time)
+		result.StartedAt = &startedAt
+	}
+	if job.FinishedAt.Valid {
+		finishedAt := strfmt.DateTime(job.FinishedAt.Time)
+		result.FinishedAt = &finishedAt
+	}
+	return &result
+}
+
+// Decode unmarshals the payload of the job
+func (job *Job) Decode(payload interface{}) error {
+	if err := json.Unmarshal(job.Payload, payload); err != nil {
+		return errors.Wrapf(err, "invalid %s payload", job.Kind)
+	}
+	return nil
+}
+
+// Option customizes an enqueued job
+type Option func(*enqueueOptions)
+
+type enqueueOptions struct {
+	dedupeKey   string
+	maxAttempts int
+	runAt       time.Time
+}
+
+// DedupeKey queues the job only when no job with the same key is queued or running. The
+// job already queued is returned instead
+func DedupeKey(key string) Option {
+	return func(o *enqueueOptions) {
+		o.dedupeKey = key
+	}
+}
+
+// MaxAttempts sets the number of attempts before the job is dead
+func MaxAttempts(n int) Option {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// RunAt delays the job until the specified time
+func RunAt(t time.Time) Option {
+	return func(o *enqueueOptions) {
+		o.runAt = t
+	}
+}
+
+// permanentError is a job failure not worth retrying
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent marks the error of a handler as permanent, the job is dead right away instead of retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	_, ok := errors.Cause(err).(*permanentError)
+	return ok
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// progressInterval throttles the progress writes of a job
+const progressInterval = time.Second
+
+type progressKey struct{}
+
+// progress records the progress of the running job
+type progress struct {
+	repo    Repository
+	job     *Job
+	mu      sync.Mutex
+	written time.Time
+}
+
+func withProgress(ctx context.Context, repo Repository, job *Job) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progress{repo: repo, job: job})
+}
+
+// ReportProgress records the progress counts of the job run by the context, with the checkpoint,
+// such as the last item processed, they were reached at. The counts are written at most once a
+// second, except the final counts when done+failed reaches total
+func ReportProgress(ctx context.Context, total, done, failed int64, checkpoint string) {
+	p, ok := ctx.Value(progressKey{}).(*progress)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if done+failed < total && time.Since(p.written) < progressInterval {
+		return
+	}
+	p.written = time.Now()
+
+	if err := p.repo.SetProgress(context.Background(), p.job, total, done, failed, checkpoint); err != nil {
+		logrus.Warnf("recording progress of %s job %d failed: %v", p.job.Kind, p.job.ID, err)
+	}
+}
+
+// Resume returns the progress counts and the checkpoint last recorded for the job run by the
+// context, by a previous attempt, so the job goes on from there. They are zero on the first attempt
+func Resume(ctx context.Context) (done, failed int64, checkpoint string) {
+	p, ok := ctx.Value(progressKey{}).(*progress)
+	if !ok {
+		return 0, 0, ""
+	}
+	return p.job.ProgressDone, p.job.ProgressFailed, p.job.Checkpoint.String
+}
+
+// ReportError records the error of an item of the job run by the context, the job goes on
+func ReportError(ctx context.Context, message string) {
+	p, ok := ctx.Value(progressKey{}).(*progress)
+	if !ok {
+		return
+	}
+
+	if err := p.repo.AddError(context.Background(), p.job, message); err != nil {
+		logrus.Warnf("recording error of %s job %d failed: %v", p.job.Kind, p.job.ID, err)
+	}
+}
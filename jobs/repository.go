@@ -0,0 +1,347 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// JobTable . . .
+	JobTable = "public.jobs"
+	// ScheduleTable . . .
+	ScheduleTable = "public.job_schedules"
+
+	defaultMaxAttempts = 5
+)
+
+var jobReturnFields = []string{
+	"id as ID",
+	"kind as Kind",
+	"payload as Payload",
+	"status as Status",
+	"attempts as Attempts",
+	"max_attempts as MaxAttempts",
+	"dedupe_key as DedupeKey",
+	"run_at as RunAt",
+	"locked_until as LockedUntil",
+	"last_error as LastError",
+	"progress_total as ProgressTotal",
+	"progress_done as ProgressDone",
+	"progress_failed as ProgressFailed",
+	"checkpoint as Checkpoint",
+	"errors as Errors",
+	"cancel_requested as CancelRequested",
+	"created_at as CreatedAt",
+	"started_at as StartedAt",
+	"finished_at as FinishedAt",
+	"updated_at as UpdatedAt",
+}
+
+// claimQuery claims the jobs ready to run, and the running jobs whose visibility timeout expired.
+// SKIP LOCKED lets concurrent workers claim different jobs without waiting for each other
+var claimQuery = `UPDATE public.jobs
+	SET status = 'running', attempts = attempts + 1, locked_until = now() + $2 * interval '1 millisecond',
+		started_at = COALESCE(started_at, now()), updated_at = now()
+	WHERE id IN (
+		SELECT id FROM public.jobs
+		WHERE (status = 'queued' AND run_at <= now()) OR (status = 'running' AND locked_until < now())
+		ORDER BY run_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + strings.Join(jobReturnFields, ", ")
+
+// enqueueQuery queues a job. A job with the dedupe key of a queued or running job is that job, the
+// no-op update returns it in the same statement
+var enqueueQuery = `INSERT INTO public.jobs (kind, payload, max_attempts, dedupe_key, run_at)
+	VALUES ($1, $2, $3, $4, COALESCE($5::timestamp, now()))
+	ON CONFLICT (dedupe_key) WHERE status IN ('queued', 'running') DO UPDATE SET dedupe_key = excluded.dedupe_key
+	RETURNING ` + strings.Join(jobReturnFields, ", ")
+
+// Repository interface includes a list of supported job queue operations
+type Repository interface {
+	Enqueue(ctx context.Context, kind string, payload interface{}, opts ...Option) (*Job, error)
+	GetJob(ctx context.Context, id int64) (*Job, error)
+	Claim(ctx context.Context, limit int, visibilityTimeout time.Duration) ([]*Job, error)
+	Renew(ctx context.Context, job *Job, visibilityTimeout time.Duration) (bool, error)
+	Complete(ctx context.Context, job *Job) error
+	Fail(ctx context.Context, job *Job, cause error, retryAt *time.Time) error
+	SetProgress(ctx context.Context, job *Job, total, done, failed int64, checkpoint string) error
+	AddError(ctx context.Context, job *Job, message string) error
+	Cancel(ctx context.Context, id int64) (*Job, error)
+	MarkCanceled(ctx context.Context, job *Job) error
+	ClaimSchedule(ctx context.Context, name string, interval time.Duration) (bool, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// Enqueue queues a job of the kind with the JSON encoded payload
+func (repo *repository) Enqueue(ctx context.Context, kind string, payload interface{}, opts ...Option) (*Job, error) {
+	o := enqueueOptions{maxAttempts: defaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "Enqueue.MarshalPayload")
+	}
+
+	var dedupeKey, runAt interface{}
+	if o.dedupeKey != "" {
+		dedupeKey = o.dedupeKey
+	}
+	if !o.runAt.IsZero() {
+		runAt = o.runAt.UTC()
+	}
+
+	job := Job{}
+	err = repo.GetDB().GetContext(ctx, &job, enqueueQuery, kind, string(body), o.maxAttempts, dedupeKey, runAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Enqueue.InsertQuery")
+	}
+
+	logrus.Debugf("queued %s job %d", job.Kind, job.ID)
+	return &job, nil
+}
+
+// GetJob returns the job by ID
+func (repo *repository) GetJob(ctx context.Context, id int64) (*Job, error) {
+	job := Job{}
+	err := sqlz.Newx(repo.GetDB()).
+		Select(jobReturnFields...).
+		From(JobTable).
+		Where(sqlz.Eq("id", id)).
+		GetRow(&job)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "GetJob")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "GetJob.SelectQuery")
+	}
+	return &job, nil
+}
+
+// Claim marks up to limit jobs as running until the visibility timeout, and returns them.
+// A job not completed or failed by then is claimed again
+func (repo *repository) Claim(ctx context.Context, limit int, visibilityTimeout time.Duration) ([]*Job, error) {
+	jobs := []*Job{}
+	err := repo.GetDB().SelectContext(ctx, &jobs, claimQuery, limit, int64(visibilityTimeout/time.Millisecond))
+	if err != nil {
+		return nil, errors.Wrap(err, "Claim.UpdateQuery")
+	}
+	return jobs, nil
+}
+
+// Renew moves the visibility timeout of the claimed job forward, and reports whether the job is
+// still claimed by this attempt
+func (repo *repository) Renew(ctx context.Context, job *Job, visibilityTimeout time.Duration) (bool, error) {
+	result, err := repo.GetDB().ExecContext(ctx, `UPDATE public.jobs
+		SET locked_until = now() + $3 * interval '1 millisecond'
+		WHERE id = $1 AND status = 'running' AND attempts = $2`,
+		job.ID, job.Attempts, int64(visibilityTimeout/time.Millisecond))
+	if err != nil {
+		return false, errors.Wrap(err, "Renew.UpdateQuery")
+	}
+	renewed, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "Renew.RowsAffected")
+	}
+	return renewed == 1, nil
+}
+
+// Complete marks the claimed job as succeeded
+func (repo *repository) Complete(ctx context.Context, job *Job) error {
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(JobTable).
+		SetMap(map[string]interface{}{
+			"status":       StatusSucceeded,
+			"locked_until": nil,
+			"last_error":   nil,
+			"finished_at":  sqlz.Indirect("now()"),
+			"updated_at":   sqlz.Indirect("now()"),
+		}).
+		Where(claimed(job)...).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "Complete.UpdateQuery")
+	}
+	return nil
+}
+
+// Fail records the failure of the claimed job. The job is queued again at retryAt, or is dead when retryAt is nil
+func (repo *repository) Fail(ctx context.Context, job *Job, cause error, retryAt *time.Time) error {
+	updateMap := map[string]interface{}{
+		"status":       StatusDead,
+		"locked_until": nil,
+		"last_error":   cause.Error(),
+		"finished_at":  sqlz.Indirect("now()"),
+		"updated_at":   sqlz.Indirect("now()"),
+	}
+	if retryAt != nil {
+		updateMap["status"] = StatusQueued
+		updateMap["run_at"] = retryAt.UTC()
+		updateMap["finished_at"] = nil
+	}
+
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(JobTable).
+		SetMap(updateMap).
+		Where(claimed(job)...).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "Fail.UpdateQuery")
+	}
+	return nil
+}
+
+// SetProgress records the progress counts of the claimed job with the checkpoint they were reached at
+func (repo *repository) SetProgress(ctx context.Context, job *Job, total, done, failed int64, checkpoint string) error {
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(JobTable).
+		SetMap(map[string]interface{}{
+			"progress_total":  total,
+			"progress_done":   done,
+			"progress_failed": failed,
+			"checkpoint":      checkpoint,
+			"updated_at":      sqlz.Indirect("now()"),
+		}).
+		Where(claimed(job)...).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "SetProgress.UpdateQuery")
+	}
+	return nil
+}
+
+// AddError appends an item error to the claimed job, keeping the first maxErrors
+func (repo *repository) AddError(ctx context.Context, job *Job, message string) error {
+	_, err := repo.GetDB().ExecContext(ctx, `UPDATE public.jobs
+		SET errors = array_append(errors, $1), updated_at = now()
+		WHERE id = $2 AND status = 'running' AND attempts = $3 AND cardinality(errors) < $4`,
+		message, job.ID, job.Attempts, maxErrors)
+	if err != nil {
+		return errors.Wrap(err, "AddError.UpdateQuery")
+	}
+	return nil
+}
+
+// Cancel cancels a queued job, or asks the worker of a running job to stop it. Finished
+// jobs are a conflict
+func (repo *repository) Cancel(ctx context.Context, id int64) (*Job, error) {
+	job := Job{}
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		err := tx.Select(jobReturnFields...).
+			From(JobTable).
+			Where(sqlz.Eq("id", id)).
+			Lock(sqlz.ForUpdate()).
+			GetRow(&job)
+		if err == sql.ErrNoRows {
+			return errs.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		updateMap := map[string]interface{}{
+			"cancel_requested": true,
+			"updated_at":       sqlz.Indirect("now()"),
+		}
+		switch job.Status {
+		case StatusQueued:
+			updateMap["status"] = StatusCanceled
+			updateMap["finished_at"] = sqlz.Indirect("now()")
+		case StatusRunning:
+		default:
+			return errors.Wrap(errs.ErrConflict, fmt.Sprintf("job %d is %s", id, job.Status))
+		}
+
+		return tx.Update(JobTable).
+			SetMap(updateMap).
+			Where(sqlz.Eq("id", id)).
+			Returning(jobReturnFields...).
+			GetRow(&job)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Cancel")
+	}
+	return &job, nil
+}
+
+// MarkCanceled records that the worker stopped the claimed job on request
+func (repo *repository) MarkCanceled(ctx context.Context, job *Job) error {
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(JobTable).
+		SetMap(map[string]interface{}{
+			"status":       StatusCanceled,
+			"locked_until": nil,
+			"finished_at":  sqlz.Indirect("now()"),
+			"updated_at":   sqlz.Indirect("now()"),
+		}).
+		Where(claimed(job)...).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "MarkCanceled.UpdateQuery")
+	}
+	return nil
+}
+
+// ClaimSchedule reports whether the run of the schedule is due, and moves its next run an interval
+// forward when it is. A single caller claims each run
+func (repo *repository) ClaimSchedule(ctx context.Context, name string, interval time.Duration) (bool, error) {
+	_, err := sqlz.Newx(repo.GetDB()).
+		InsertInto(ScheduleTable).
+		ValueMap(map[string]interface{}{"name": name}).
+		OnConflictDoNothing().
+		Exec()
+	if err != nil {
+		return false, errors.Wrap(err, "ClaimSchedule.InsertQuery")
+	}
+
+	result, err := repo.GetDB().ExecContext(ctx, `UPDATE public.job_schedules
+		SET next_run_at = now() + $2 * interval '1 millisecond'
+		WHERE name = $1 AND next_run_at <= now()`,
+		name, int64(interval/time.Millisecond))
+	if err != nil {
+		return false, errors.Wrap(err, "ClaimSchedule.UpdateQuery")
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "ClaimSchedule.RowsAffected")
+	}
+	return claimed == 1, nil
+}
+
+// claimed matches the job only while it is still claimed by this attempt, a job claimed again
+// after its visibility timeout expired belongs to the new attempt
+func claimed(job *Job) []sqlz.WhereCondition {
+	return []sqlz.WhereCondition{
+		sqlz.Eq("id", job.ID),
+		sqlz.Eq("status", StatusRunning),
+		sqlz.Eq("attempts", job.Attempts),
+	}
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// schedulePollInterval is how often the schedules are checked for a due run
+const schedulePollInterval = time.Minute
+
+type schedule struct {
+	kind     string
+	interval time.Duration
+	payload  interface{}
+}
+
+// Schedule queues a job of the kind with the payload every interval. The runs are shared by all the
+// workers of the database, the schedule runs once per interval however many workers are running
+func (w *Worker) Schedule(kind string, interval time.Duration, payload interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.schedules = append(w.schedules, schedule{kind: kind, interval: interval, payload: payload})
+}
+
+// runSchedules queues the due runs of the schedules until the context is done
+func (w *Worker) runSchedules(ctx context.Context) {
+	w.mu.RLock()
+	schedules := append([]schedule(nil), w.schedules...)
+	w.mu.RUnlock()
+	if len(schedules) == 0 {
+		return
+	}
+
+	for {
+		for _, s := range schedules {
+			w.enqueueDue(ctx, s)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(schedulePollInterval):
+		}
+	}
+}
+
+func (w *Worker) enqueueDue(ctx context.Context, s schedule) {
+	due, err := w.repo.ClaimSchedule(ctx, s.kind, s.interval)
+	if err != nil {
+		if ctx.Err() == nil {
+			logrus.Errorf("checking the %s schedule failed: %v", s.kind, err)
+		}
+		return
+	}
+	if !due {
+		return
+	}
+
+	// a run still queued or running from the previous interval is not queued again
+	job, err := w.repo.Enqueue(ctx, s.kind, s.payload, DedupeKey(s.kind))
+	if err != nil {
+		logrus.Errorf("queuing the scheduled %s job failed: %v", s.kind, err)
+		return
+	}
+	logrus.Infof("queued scheduled %s job %d", s.kind, job.ID)
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/job"
+	"github.com/pkg/errors"
+)
+
+// Service interface is a list of services for the jobs of the long running operations
+type Service interface {
+	GetJob(ctx context.Context, in *job.GetJobParams) (*models.Job, error)
+	CancelJob(ctx context.Context, in *job.CancelJobParams) (*models.Job, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// New is a simple helper function to create a service instance
+func New(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// GetJob service definition
+func (s *service) GetJob(ctx context.Context, in *job.GetJobParams) (*models.Job, error) {
+	log.Debugf("entered service GetJob")
+	if err := auth.Authorize(ctx, auth.ReadJobs); err != nil {
+		return nil, errors.Wrap(err, "service.GetJob")
+	}
+	result, err := s.repo.GetJob(ctx, in.ID)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.GetJob")
+	}
+	return result.ToJob(), nil
+}
+
+// CancelJob service definition
+func (s *service) CancelJob(ctx context.Context, in *job.CancelJobParams) (*models.Job, error) {
+	log.Debugf("entered service CancelJob")
+	if err := auth.Authorize(ctx, auth.CancelJobs); err != nil {
+		return nil, errors.Wrap(err, "service.CancelJob")
+	}
+	result, err := s.repo.Cancel(ctx, in.ID)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.CancelJob")
+	}
+	return result.ToJob(), nil
+}
@@ -0,0 +1,289 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// HandlerFunc runs a job of the kind it is registered for. Returning an error retries the
+// job with exponential backoff, unless the error is Permanent
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+// Config configures the workers
+type Config struct {
+	// Workers is the number of jobs run concurrently
+	Workers int
+	// PollInterval is the wait before polling again when no job is ready
+	PollInterval time.Duration
+	// VisibilityTimeout is how long a job is claimed by a worker. The claim of a running job is
+	// renewed every third of it, so only the jobs of a stopped worker are claimed again
+	VisibilityTimeout time.Duration
+	// HandlerTimeout is the timeout of a handler, however long its claim is renewed
+	HandlerTimeout time.Duration
+	// BaseBackoff is the delay of the first retry, doubled on every further attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// CancelPollInterval is how often a running job is checked for a cancellation request
+	CancelPollInterval time.Duration
+}
+
+// DefaultConfig is the worker configuration used for the values not set
+var DefaultConfig = Config{
+	Workers:            2,
+	PollInterval:       time.Second,
+	VisibilityTimeout:  5 * time.Minute,
+	HandlerTimeout:     time.Hour,
+	BaseBackoff:        10 * time.Second,
+	MaxBackoff:         time.Hour,
+	CancelPollInterval: 2 * time.Second,
+}
+
+// Worker claims the queued jobs and runs their handlers
+type Worker struct {
+	repo      Repository
+	cfg       Config
+	mu        sync.RWMutex
+	handlers  map[string]HandlerFunc
+	schedules []schedule
+}
+
+// NewWorker creates a worker of the jobs of the repository
+func NewWorker(repo Repository, cfg Config) *Worker {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultConfig.Workers
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig.PollInterval
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = DefaultConfig.VisibilityTimeout
+	}
+	if cfg.HandlerTimeout <= 0 {
+		cfg.HandlerTimeout = DefaultConfig.HandlerTimeout
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultConfig.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultConfig.MaxBackoff
+	}
+	if cfg.CancelPollInterval <= 0 {
+		cfg.CancelPollInterval = DefaultConfig.CancelPollInterval
+	}
+
+	return &Worker{
+		repo:     repo,
+		cfg:      cfg,
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+// Register sets the handler of the jobs of the kind
+func (w *Worker) Register(kind string, handler HandlerFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[kind] = handler
+}
+
+func (w *Worker) handler(kind string) HandlerFunc {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.handlers[kind]
+}
+
+// Run runs the jobs with the configured number of goroutines, and queues the scheduled jobs, until
+// the context is done
+func (w *Worker) Run(ctx context.Context) {
+	logrus.Infof("Starting %d job workers", w.cfg.Workers)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.runSchedules(ctx)
+	}()
+	for i := 0; i < w.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	logrus.Infof("Job workers stopped")
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := w.repo.Claim(ctx, 1, w.cfg.VisibilityTimeout)
+		if err != nil && ctx.Err() == nil {
+			logrus.Errorf("claiming jobs failed: %v", err)
+		}
+
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.cfg.PollInterval):
+			}
+			continue
+		}
+
+		for _, job := range jobs {
+			w.run(ctx, job)
+		}
+	}
+}
+
+// run runs the handler of the job and records the outcome. The outcome is recorded even when
+// the worker is stopping, so the job does not wait for its visibility timeout
+func (w *Worker) run(ctx context.Context, job *Job) {
+	if job.CancelRequested {
+		w.canceled(job)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	requested := w.watchCancel(ctx, cancel, job)
+	go w.renewClaim(ctx, cancel, job)
+
+	err := w.handle(ctx, job)
+	if requested() {
+		w.canceled(job)
+		return
+	}
+	if err == nil {
+		if errComplete := w.repo.Complete(context.Background(), job); errComplete != nil {
+			logrus.Errorf("completing %s job %d failed: %v", job.Kind, job.ID, errComplete)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if !isPermanent(err) && job.Attempts < job.MaxAttempts {
+		next := time.Now().Add(w.backoff(job.Attempts))
+		retryAt = &next
+		logrus.Warnf("%s job %d attempt %d failed, retrying at %s: %v", job.Kind, job.ID, job.Attempts, next, err)
+	} else {
+		logrus.Errorf("%s job %d is dead after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+	}
+
+	if errFail := w.repo.Fail(context.Background(), job, err, retryAt); errFail != nil {
+		logrus.Errorf("failing %s job %d failed: %v", job.Kind, job.ID, errFail)
+	}
+}
+
+// watchCancel cancels the context when a cancellation of the job is requested, until the
+// context is done. The returned func reports whether the cancellation was requested
+func (w *Worker) watchCancel(ctx context.Context, cancel context.CancelFunc, job *Job) func() bool {
+	var mu sync.Mutex
+	requested := false
+
+	go func() {
+		ticker := time.NewTicker(w.cfg.CancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := w.repo.GetJob(ctx, job.ID)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.Warnf("checking %s job %d for cancellation failed: %v", job.Kind, job.ID, err)
+				}
+				continue
+			}
+			if current.CancelRequested {
+				mu.Lock()
+				requested = true
+				mu.Unlock()
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return requested
+	}
+}
+
+// renewClaim moves the visibility timeout of the running job forward every third of it, until
+// the context is done. The handler is stopped when the job is no longer claimed by this attempt
+func (w *Worker) renewClaim(ctx context.Context, cancel context.CancelFunc, job *Job) {
+	ticker := time.NewTicker(w.cfg.VisibilityTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		claimed, err := w.repo.Renew(ctx, job, w.cfg.VisibilityTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.Warnf("renewing the claim of %s job %d failed: %v", job.Kind, job.ID, err)
+			}
+			continue
+		}
+		if !claimed {
+			logrus.Warnf("%s job %d attempt %d lost its claim, stopping it", job.Kind, job.ID, job.Attempts)
+			cancel()
+			return
+		}
+	}
+}
+
+func (w *Worker) canceled(job *Job) {
+	logrus.Infof("%s job %d canceled", job.Kind, job.ID)
+	if err := w.repo.MarkCanceled(context.Background(), job); err != nil {
+		logrus.Errorf("canceling %s job %d failed: %v", job.Kind, job.ID, err)
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, job *Job) (err error) {
+	// a job claimed again after its visibility timeout may have used its last attempt
+	if job.Attempts > job.MaxAttempts {
+		return Permanent(errors.New("visibility timeout expired on the last attempt"))
+	}
+
+	handler := w.handler(job.Kind)
+	if handler == nil {
+		return Permanent(errors.Errorf("no handler registered for job kind %s", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.HandlerTimeout)
+	defer cancel()
+	return handler(withProgress(ctx, w.repo, job), job)
+}
+
+// backoff returns the delay before the retry of the attempt, doubling from BaseBackoff up to
+// MaxBackoff, with up to 20% jitter so the retries of jobs failing together spread out
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := float64(w.cfg.BaseBackoff) * math.Pow(2, float64(attempt-1))
+	if delay > float64(w.cfg.MaxBackoff) {
+		delay = float64(w.cfg.MaxBackoff)
+	}
+	delay *= 1 + 0.2*rand.Float64()
+	return time.Duration(delay)
+}
@@ -1,20 +1,31 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/go-openapi/loads"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/movieManagement/apikey"
+	"github.com/movieManagement/auth"
 	"github.com/movieManagement/cmd"
 	"github.com/movieManagement/gen/restapi"
 	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/genre"
 	"github.com/movieManagement/health"
+	"github.com/movieManagement/jobs"
+	"github.com/movieManagement/metacache"
+	"github.com/movieManagement/migration"
 	"github.com/movieManagement/movie"
+	"github.com/movieManagement/provider"
+	"github.com/movieManagement/review"
+	"github.com/movieManagement/watchlist"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -37,6 +48,49 @@ func setupDefaults() map[string]interface{} {
 		"USE_MOCK":           "False",
 		"HCDB":               "host=localhost port=3306 dbname=pmm sslmode=disable application_name='pmm'",
 		"DB_MAX_CONNECTIONS": 20,
+		// number of days a soft deleted movie is kept before it can be purged
+		"PURGE_RETENTION_DAYS": 30,
+		// apply the pending schema migrations on startup, e.g. for Lambda deployments
+		"MIGRATE_ON_START": false,
+		// ordered metadata providers looking up the movies missing from the database - omdb, rapidapi, fake or none
+		"METADATA_PROVIDERS": provider.OMDb + "," + provider.RapidAPI,
+		// the fields looked up until a provider supplies them, provider.DefaultRequiredFields when empty
+		"METADATA_REQUIRED_FIELDS": "",
+		"OMDB_API_KEY":             "",
+		"RAPIDAPI_KEY":             "",
+		"RAPIDAPI_HOST":            provider.DefaultRapidAPIHost,
+		// provider quotas as <count>/<s|min|hour|day>, the free tiers by default, and circuit breakers
+		"OMDB_RATE_LIMIT":               "1000/day",
+		"OMDB_RATE_BURST":               10,
+		"RAPIDAPI_RATE_LIMIT":           "1000/day",
+		"RAPIDAPI_RATE_BURST":           10,
+		"PROVIDER_TIMEOUT_SECONDS":      5,
+		"PROVIDER_BREAKER_FAILURES":     5,
+		"PROVIDER_BREAKER_OPEN_SECONDS": 30,
+		// metadata lookup cache, 0 hours disables it. Not found answers are kept for the negative TTL
+		"METADATA_CACHE_TTL_HOURS":          168,
+		"METADATA_CACHE_NEGATIVE_TTL_HOURS": 24,
+		"METADATA_CACHE_SIZE":               1000,
+		// number of job workers run by the HTTP server, 0 when the jobs are run by the worker command
+		"JOB_WORKERS":                    2,
+		"JOB_VISIBILITY_TIMEOUT_SECONDS": 300,
+		"JOB_HANDLER_TIMEOUT_MINUTES":    60,
+		// stale movie refresh: every interval (0 disables it), up to budget movies not refreshed for max age days.
+		// The budget stays within the default provider quota of 1000/day, about 41 lookups an hour
+		"REFRESH_INTERVAL_MINUTES": 60,
+		"REFRESH_MAX_AGE_DAYS":     30,
+		"REFRESH_BUDGET":           40,
+		// JWT bearer authentication: comma separated HS256 secrets and/or the JWKS of the RS256 keys, a file or URL
+		"JWT_HS256_SECRETS":        "",
+		"JWT_JWKS_FILE":            "",
+		"JWT_JWKS_URL":             "",
+		"JWT_JWKS_REFRESH_MINUTES": 60,
+		"JWT_ISSUER":               "",
+		"JWT_AUDIENCE":             "",
+		"JWT_LEEWAY_SECONDS":       60,
+		// the claim of the viewer, editor or admin roles of the users, the default role of the users without any
+		"JWT_ROLES_CLAIM":  "roles",
+		"JWT_DEFAULT_ROLE": auth.RoleViewer,
 	}
 
 	for key, value := range defaults {
@@ -63,6 +117,17 @@ func getProperty(property string) string {
 	return value
 }
 
+// providerLimits returns the rate limit and circuit breaker settings of the provider
+func providerLimits(prefix string) provider.Limits {
+	return provider.Limits{
+		Rate:             viper.GetString(prefix + "_RATE_LIMIT"),
+		Burst:            viper.GetInt(prefix + "_RATE_BURST"),
+		Timeout:          time.Duration(viper.GetInt("PROVIDER_TIMEOUT_SECONDS")) * time.Second,
+		FailureThreshold: viper.GetInt("PROVIDER_BREAKER_FAILURES"),
+		OpenDuration:     time.Duration(viper.GetInt("PROVIDER_BREAKER_OPEN_SECONDS")) * time.Second,
+	}
+}
+
 func initDB(name string) *sqlx.DB {
 	hcDBURL := getProperty(name)
 	logrus.Infof("Initializing DB %s connection with URL (prefix) %s...", name, hcDBURL[0:11])
@@ -90,6 +155,7 @@ func main() {
 	logrus.Infof("Service Startup")
 
 	var portFlag = flag.Int("port", viper.GetInt("PORT"), "Port to listen for web requests on")
+	flag.Parse()
 
 	// Show the version and build info
 	logrus.Infof("Version               : %s", version)
@@ -108,24 +174,124 @@ func main() {
 	// Initialize hcDB connection
 	hcDB := initDB("HCDB")
 
+	// Run the migrate subcommand, or make sure the schema is up to date before serving
+	migrator, err := migration.New(hcDB)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := cmd.Migrate(migrator, flag.Args()[1:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+	if viper.GetBool("MIGRATE_ON_START") {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		logrus.Fatal(err)
+	}
+
 	api := operations.NewMovieServiceAPI(swaggerSpec)
 
+	// Setup the bearer and API key authentication of the secured operations
+	verifier, err := auth.NewVerifier(auth.Config{
+		Secrets:     strings.Split(viper.GetString("JWT_HS256_SECRETS"), ","),
+		JWKSFile:    viper.GetString("JWT_JWKS_FILE"),
+		JWKSURL:     viper.GetString("JWT_JWKS_URL"),
+		JWKSRefresh: time.Duration(viper.GetInt("JWT_JWKS_REFRESH_MINUTES")) * time.Minute,
+		Issuer:      viper.GetString("JWT_ISSUER"),
+		Audience:    viper.GetString("JWT_AUDIENCE"),
+		Leeway:      time.Duration(viper.GetInt("JWT_LEEWAY_SECONDS")) * time.Second,
+		RolesClaim:  viper.GetString("JWT_ROLES_CLAIM"),
+		DefaultRole: viper.GetString("JWT_DEFAULT_ROLE"),
+	})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	auth.Configure(api, verifier)
+	apikey.Configure(api, apikey.New(apikey.NewRepository(hcDB)))
+
+	// Setup the metadata provider chain, movies are not looked up when no provider is configured
+	metadataProvider, err := provider.New(provider.Config{
+		Providers:      viper.GetString("METADATA_PROVIDERS"),
+		RequiredFields: viper.GetString("METADATA_REQUIRED_FIELDS"),
+		OMDbAPIKey:     viper.GetString("OMDB_API_KEY"),
+		OMDbLimits:     providerLimits("OMDB"),
+		RapidAPIKey:    viper.GetString("RAPIDAPI_KEY"),
+		RapidAPIHost:   viper.GetString("RAPIDAPI_HOST"),
+		RapidAPILimits: providerLimits("RAPIDAPI"),
+	})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// Cache the metadata lookups, including the not found answers
+	metadataCache := metacache.New(metacache.NewRepository(hcDB), metacache.Config{
+		TTL:         time.Duration(viper.GetInt("METADATA_CACHE_TTL_HOURS")) * time.Hour,
+		NegativeTTL: time.Duration(viper.GetInt("METADATA_CACHE_NEGATIVE_TTL_HOURS")) * time.Hour,
+		Size:        viper.GetInt("METADATA_CACHE_SIZE"),
+	})
+	metadataProvider = metadataCache.Wrap(metadataProvider)
+	metacache.Configure(api, metacache.NewService(metadataCache))
+	if metadataProvider == nil {
+		logrus.Warnf("No metadata provider configured, movies missing from the database are not looked up")
+	} else {
+		logrus.Infof("Metadata provider     : %s", metadataProvider.Name())
+	}
+
+	// Setup the job queue
+	jobRepo := jobs.NewRepository(hcDB)
+	worker := jobs.NewWorker(jobRepo, jobs.Config{
+		Workers:           viper.GetInt("JOB_WORKERS"),
+		VisibilityTimeout: time.Duration(viper.GetInt("JOB_VISIBILITY_TIMEOUT_SECONDS")) * time.Second,
+		HandlerTimeout:    time.Duration(viper.GetInt("JOB_HANDLER_TIMEOUT_MINUTES")) * time.Minute,
+	})
+
 	// Setup the movie service
 	movieRepo := movie.NewRepository(hcDB)
-	movieService := movie.New(movieRepo)
+	movieService := movie.New(movieRepo, time.Duration(viper.GetInt("PURGE_RETENTION_DAYS"))*24*time.Hour, metadataProvider, movie.NewJobEnqueuer(jobRepo))
 	movie.Configure(api, movieService)
+	movie.RegisterJobs(worker, movieService)
+	if interval := viper.GetInt("REFRESH_INTERVAL_MINUTES"); interval > 0 && metadataProvider != nil {
+		movie.ScheduleRefresh(worker,
+			time.Duration(interval)*time.Minute,
+			time.Duration(viper.GetInt("REFRESH_MAX_AGE_DAYS"))*24*time.Hour,
+			viper.GetInt("REFRESH_BUDGET"))
+	}
+	jobs.Configure(api, jobs.New(jobRepo))
+
+	// Run the worker subcommand, or the job workers next to the HTTP server
+	if flag.Arg(0) == "worker" {
+		if err := cmd.Work(worker); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+	if viper.GetInt("JOB_WORKERS") > 0 {
+		go worker.Run(context.Background())
+	}
+
+	// Setup the genre taxonomy service
+	genreRepo := genre.NewRepository(hcDB)
+	genre.Configure(api, genre.New(genreRepo))
+
+	// Setup the watchlists of the users
+	watchlist.Configure(api, watchlist.New(watchlist.NewRepository(hcDB)))
+
+	// Setup the reviews of the movies and their community rating
+	review.Configure(api, review.New(review.NewRepository(hcDB)))
 
 	// Setup the health service
 	var healthService health.Service
 	if viper.GetBool("USE_MOCK") {
 		healthService = health.NewMock()
 	} else {
-		healthService = health.New(hcDB, commit, buildDate)
+		healthService = health.New(hcDB, commit, buildDate, metadataProvider)
 	}
 	health.Configure(api, healthService)
 
-	flag.Parse()
-
 	if err := cmd.Start(api, *portFlag); err != nil {
 		logrus.Fatal(err)
 	}
@@ -0,0 +1,190 @@
+package metacache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/provider"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Config configures the metadata cache
+type Config struct {
+	// TTL is how long a lookup is cached, 0 disables the cache
+	TTL time.Duration
+	// NegativeTTL is how long a not found answer is cached
+	NegativeTTL time.Duration
+	// Size is the number of lookups kept in the in-process cache
+	Size int
+}
+
+// Cache caches the metadata lookups in an in-process LRU in front of the repository, which is
+// shared by the instances. Not found answers are cached as well, for NegativeTTL
+type Cache struct {
+	repo   Repository
+	cfg    Config
+	memory *lru
+	since  time.Time
+
+	hits         int64
+	negativeHits int64
+	misses       int64
+}
+
+// New creates the metadata cache
+func New(repo Repository, cfg Config) *Cache {
+	return &Cache{
+		repo:   repo,
+		cfg:    cfg,
+		memory: newLRU(cfg.Size),
+		since:  time.Now(),
+	}
+}
+
+// Wrap returns the provider looking up the movies through the cache, or the provider itself when
+// the cache is disabled
+func (c *Cache) Wrap(metadata provider.MetadataProvider) provider.MetadataProvider {
+	if metadata == nil || c.cfg.TTL <= 0 {
+		return metadata
+	}
+	return &cachedProvider{cache: c, next: metadata}
+}
+
+// Key returns the cache key of the lookup of the title narrowed to the identity. The lookups of an
+// IMDb ID share their entry, and so do the lookups of titles differing only in case and spacing,
+// of the same year
+func Key(title string, identity provider.Identity) string {
+	if imdbID := strings.ToLower(strings.TrimSpace(identity.ImdbID)); imdbID != "" {
+		return "imdb:" + imdbID
+	}
+	key := "title:" + strings.Join(strings.Fields(strings.ToLower(title)), " ")
+	if year := strings.TrimSpace(identity.Year); year != "" {
+		key += " (" + year + ")"
+	}
+	return key
+}
+
+// Stats returns the entries of the cache, and the hits and misses of this instance
+func (c *Cache) Stats(ctx context.Context) (*models.MetadataCacheStats, error) {
+	entries, negative, expired, err := c.repo.Count(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Stats")
+	}
+
+	return &models.MetadataCacheStats{
+		Entries:         entries,
+		NegativeEntries: negative,
+		ExpiredEntries:  expired,
+		MemoryEntries:   int64(c.memory.len()),
+		MemoryCapacity:  int64(c.cfg.Size),
+		Hits:            atomic.LoadInt64(&c.hits),
+		NegativeHits:    atomic.LoadInt64(&c.negativeHits),
+		Misses:          atomic.LoadInt64(&c.misses),
+		Since:           strfmt.DateTime(c.since),
+	}, nil
+}
+
+// Purge deletes the lookups of the title, of any year, or all the lookups when title is empty. Only
+// the expired lookups are deleted with expiredOnly
+func (c *Cache) Purge(ctx context.Context, title string, expiredOnly bool) (int64, error) {
+	key := ""
+	if strings.TrimSpace(title) != "" {
+		key = Key(title, provider.Identity{})
+	}
+
+	purged, err := c.repo.Purge(ctx, key, expiredOnly)
+	if err != nil {
+		return 0, errors.Wrap(err, "Purge")
+	}
+
+	if key != "" && !expiredOnly {
+		c.memory.remove(key)
+	} else {
+		c.memory.purge(expiredOnly, time.Now())
+	}
+	logrus.Infof("purged %d metadata cache entries", purged)
+	return purged, nil
+}
+
+// lookup returns the cached entry of the key, nil when there is none. A repository failure is a miss
+func (c *Cache) lookup(ctx context.Context, key string) *Entry {
+	if entry, ok := c.memory.get(key, time.Now()); ok {
+		return entry
+	}
+
+	entry, err := c.repo.Get(ctx, key)
+	if errors.Cause(err) == errs.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		logrus.Warnf("metadata cache lookup of %s failed: %v", key, err)
+		return nil
+	}
+	c.memory.add(key, entry)
+	return entry
+}
+
+func (c *Cache) store(ctx context.Context, key string, metadata *provider.MovieMetadata) {
+	ttl := c.cfg.TTL
+	if metadata == nil {
+		ttl = c.cfg.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	fetchedAt := time.Now()
+	entry := &Entry{Metadata: metadata, FetchedAt: fetchedAt, ExpiresAt: fetchedAt.Add(ttl)}
+	c.memory.add(key, entry)
+	if err := c.repo.Put(ctx, key, entry); err != nil {
+		logrus.Warnf("metadata cache store of %s failed: %v", key, err)
+	}
+}
+
+type cachedProvider struct {
+	cache *Cache
+	next  provider.MetadataProvider
+}
+
+func (p *cachedProvider) Name() string {
+	return p.next.Name()
+}
+
+func (p *cachedProvider) Statuses() []provider.Status {
+	return provider.Statuses(p.next)
+}
+
+// MovieByTitle answers from the cache, unless the context asks for a fresh lookup. The answers of
+// the provider, including not found, are cached, its failures are not
+func (p *cachedProvider) MovieByTitle(ctx context.Context, title string) (*provider.MovieMetadata, error) {
+	key := Key(title, provider.IdentityOf(ctx))
+	if !provider.IsFresh(ctx) {
+		if entry := p.cache.lookup(ctx, key); entry != nil {
+			atomic.AddInt64(&p.cache.hits, 1)
+			if entry.NotFound() {
+				atomic.AddInt64(&p.cache.negativeHits, 1)
+				return nil, errors.Wrap(errs.ErrNotFound, "cached")
+			}
+			return clone(entry.Metadata), nil
+		}
+	}
+
+	atomic.AddInt64(&p.cache.misses, 1)
+	metadata, err := p.next.MovieByTitle(ctx, title)
+	if errors.Cause(err) == errs.ErrNotFound {
+		p.cache.store(ctx, key, nil)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.cache.store(ctx, key, clone(metadata))
+	return metadata, nil
+}
@@ -0,0 +1,50 @@
+package metacache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/movieManagement/provider"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		title    string
+		identity provider.Identity
+		want     string
+	}{
+		{title: "  The   Matrix ", want: "title:the matrix"},
+		{title: "Dune", identity: provider.Identity{Year: "2021"}, want: "title:dune (2021)"},
+		{title: "DUNE", identity: provider.Identity{Year: " 1984 "}, want: "title:dune (1984)"},
+		{title: "Dune", identity: provider.Identity{Year: "2021", ImdbID: " TT1160419"}, want: "imdb:tt1160419"},
+		{title: "Dune: Part Two", identity: provider.Identity{ImdbID: "tt15239678"}, want: "imdb:tt15239678"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.title, tt.identity); got != tt.want {
+			t.Errorf("Key(%q, %+v) = %q, want %q", tt.title, tt.identity, got, tt.want)
+		}
+	}
+}
+
+func TestLRURemoveTitle(t *testing.T) {
+	c := newLRU(10)
+	entry := &Entry{ExpiresAt: time.Now().Add(time.Hour)}
+	keys := []string{
+		Key("Dune", provider.Identity{}),
+		Key("Dune", provider.Identity{Year: "2021"}),
+		Key("Dune", provider.Identity{Year: "1984"}),
+		Key("Dune Messiah", provider.Identity{}),
+		Key("Dune", provider.Identity{ImdbID: "tt1160419"}),
+	}
+	for _, key := range keys {
+		c.add(key, entry)
+	}
+
+	c.remove(Key("Dune", provider.Identity{}))
+	for i, key := range keys {
+		_, ok := c.get(key, time.Now())
+		if want := i >= 3; ok != want {
+			t.Errorf("%s kept: %v, want %v", key, ok, want)
+		}
+	}
+}
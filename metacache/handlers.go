@@ -0,0 +1,29 @@
+package metacache
+
+import (
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/admin"
+	"github.com/movieManagement/swagger"
+)
+
+// Configure configures the metadata cache administration service
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.AdminGetMetadataCacheStatsHandler = admin.GetMetadataCacheStatsHandlerFunc(func(params admin.GetMetadataCacheStatsParams, principal *models.Principal) middleware.Responder {
+		result, err := service.GetStats(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("GetMetadataCacheStats :: ", err)
+		}
+		return admin.NewGetMetadataCacheStatsOK().WithPayload(result)
+	})
+
+	api.AdminPurgeMetadataCacheHandler = admin.PurgeMetadataCacheHandlerFunc(func(params admin.PurgeMetadataCacheParams, principal *models.Principal) middleware.Responder {
+		result, err := service.Purge(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("PurgeMetadataCache :: ", err)
+		}
+		return admin.NewPurgeMetadataCacheOK().WithPayload(result)
+	})
+}
@@ -0,0 +1,99 @@
+package metacache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lru is the bounded in-process cache in front of the repository, evicting the least recently used lookups
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+func newLRU(capacity int) *lru {
+	return &lru{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the unexpired entry of the key
+func (c *lru) get(key string, now time.Time) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := element.Value.(*lruItem)
+	if item.entry.expired(now) {
+		c.order.Remove(element)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (c *lru) add(key string, entry *Entry) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// remove removes the entry of the title key and the entries of its years
+func (c *lru) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for itemKey, element := range c.items {
+		if itemKey == key || strings.HasPrefix(itemKey, key+" (") {
+			c.order.Remove(element)
+			delete(c.items, itemKey)
+		}
+	}
+}
+
+// purge removes the expired entries, or all of them
+func (c *lru) purge(expiredOnly bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, element := range c.items {
+		if !expiredOnly || element.Value.(*lruItem).entry.expired(now) {
+			c.order.Remove(element)
+			delete(c.items, key)
+		}
+	}
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,72 @@
+package metacache
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/movieManagement/provider"
+	"github.com/pkg/errors"
+)
+
+// Entry is a cached lookup. A nil Metadata is a not found answer
+type Entry struct {
+	Metadata  *provider.MovieMetadata
+	FetchedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NotFound reports whether the providers did not know the movie
+func (e *Entry) NotFound() bool {
+	return e.Metadata == nil
+}
+
+func (e *Entry) expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// SQLEntry . . . The expiry is read as the time remaining, the database clock may differ from ours
+type SQLEntry struct {
+	Key             string
+	Metadata        types.NullJSONText
+	FetchedAt       time.Time
+	RemainingMillis int64
+}
+
+func (sql *SQLEntry) toEntry() (*Entry, error) {
+	entry := Entry{
+		FetchedAt: sql.FetchedAt,
+		ExpiresAt: time.Now().Add(time.Duration(sql.RemainingMillis) * time.Millisecond),
+	}
+	if sql.Metadata.Valid {
+		metadata := provider.MovieMetadata{}
+		if err := json.Unmarshal(sql.Metadata.JSONText, &metadata); err != nil {
+			return nil, errors.Wrapf(err, "invalid cached metadata %s", sql.Key)
+		}
+		entry.Metadata = &metadata
+	}
+	return &entry, nil
+}
+
+// clone copies the metadata, so the callers can not change the cached values
+func clone(metadata *provider.MovieMetadata) *provider.MovieMetadata {
+	copied := *metadata
+	copied.Genres = append([]string(nil), metadata.Genres...)
+	copied.Writers = append([]string(nil), metadata.Writers...)
+	copied.Actors = append([]string(nil), metadata.Actors...)
+	if metadata.Metascore != nil {
+		metascore := *metadata.Metascore
+		copied.Metascore = &metascore
+	}
+	if metadata.Runtime != nil {
+		runtime := *metadata.Runtime
+		copied.Runtime = &runtime
+	}
+	if metadata.Sources != nil {
+		copied.Sources = make(map[string]provider.Source, len(metadata.Sources))
+		for field, source := range metadata.Sources {
+			copied.Sources[field] = source
+		}
+	}
+	return &copied
+}
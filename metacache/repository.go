@@ -0,0 +1,134 @@
+package metacache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CacheTable . . .
+	CacheTable = "public.metadata_cache"
+)
+
+var entryReturnFields = []string{
+	"key as Key",
+	"metadata as Metadata",
+	"fetched_at as FetchedAt",
+	"(extract(epoch FROM expires_at - now()) * 1000)::bigint as RemainingMillis",
+}
+
+// Repository interface includes a list of supported metadata cache operations
+type Repository interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Put(ctx context.Context, key string, entry *Entry) error
+	Purge(ctx context.Context, key string, expiredOnly bool) (int64, error)
+	Count(ctx context.Context) (entries, negative, expired int64, err error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// Get returns the unexpired entry of the key, errs.ErrNotFound when there is none
+func (repo *repository) Get(ctx context.Context, key string) (*Entry, error) {
+	sqlEntry := SQLEntry{}
+	err := sqlz.Newx(repo.GetDB()).
+		Select(entryReturnFields...).
+		From(CacheTable).
+		Where(sqlz.Eq("key", key), sqlz.SQLCond("expires_at > now()")).
+		GetRow(&sqlEntry)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "Get")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Get.SelectQuery")
+	}
+	return sqlEntry.toEntry()
+}
+
+// Put stores the entry of the key, replacing the previous one. The entry is kept for as long as
+// from its FetchedAt to its ExpiresAt, measured with the database clock as the expiry checks are
+func (repo *repository) Put(ctx context.Context, key string, entry *Entry) error {
+	var metadata interface{}
+	if entry.Metadata != nil {
+		body, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return errors.Wrap(err, "Put.MarshalMetadata")
+		}
+		metadata = string(body)
+	}
+
+	ttl := entry.ExpiresAt.Sub(entry.FetchedAt)
+	_, err := repo.GetDB().ExecContext(ctx, `INSERT INTO public.metadata_cache (key, metadata, fetched_at, expires_at)
+		VALUES ($1, $2, now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET metadata = excluded.metadata, fetched_at = excluded.fetched_at, expires_at = excluded.expires_at`,
+		key, metadata, int64(ttl/time.Millisecond))
+	if err != nil {
+		return errors.Wrap(err, "Put.InsertQuery")
+	}
+	return nil
+}
+
+// Purge deletes the entry of the title key and the entries of its years, or all the entries when
+// key is empty. Only the expired entries are deleted with expiredOnly
+func (repo *repository) Purge(ctx context.Context, key string, expiredOnly bool) (int64, error) {
+	conditions := []sqlz.WhereCondition{}
+	if key != "" {
+		years := key + " ("
+		conditions = append(conditions, sqlz.Or(
+			sqlz.Eq("key", key),
+			sqlz.SQLCond("left(key, ?) = ?", utf8.RuneCountInString(years), years),
+		))
+	}
+	if expiredOnly {
+		conditions = append(conditions, sqlz.SQLCond("expires_at <= now()"))
+	}
+
+	query := sqlz.Newx(repo.GetDB()).DeleteFrom(CacheTable)
+	if len(conditions) != 0 {
+		query = query.Where(conditions...)
+	}
+	res, err := query.Exec()
+	if err != nil {
+		return 0, errors.Wrap(err, "Purge.DeleteQuery")
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "Purge.RowsAffected")
+	}
+	return purged, nil
+}
+
+// Count returns the number of unexpired entries, of the unexpired not found entries and of the expired entries
+func (repo *repository) Count(ctx context.Context) (entries, negative, expired int64, err error) {
+	row := repo.GetDB().QueryRowxContext(ctx, `SELECT
+		count(*) FILTER (WHERE expires_at > now()),
+		count(*) FILTER (WHERE expires_at > now() AND metadata IS NULL),
+		count(*) FILTER (WHERE expires_at <= now())
+		FROM public.metadata_cache`)
+	if err = row.Scan(&entries, &negative, &expired); err != nil {
+		return 0, 0, 0, errors.Wrap(err, "Count.SelectQuery")
+	}
+	return entries, negative, expired, nil
+}
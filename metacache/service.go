@@ -0,0 +1,62 @@
+package metacache
+
+import (
+	"context"
+
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/admin"
+	"github.com/pkg/errors"
+)
+
+// Service interface is a list of services for the metadata cache administration
+type Service interface {
+	GetStats(ctx context.Context, in *admin.GetMetadataCacheStatsParams) (*models.MetadataCacheStats, error)
+	Purge(ctx context.Context, in *admin.PurgeMetadataCacheParams) (*models.MetadataCachePurgeResult, error)
+}
+
+type service struct {
+	cache *Cache
+}
+
+// NewService is a simple helper function to create a service instance
+func NewService(cache *Cache) Service {
+	return &service{
+		cache: cache,
+	}
+}
+
+// GetStats service definition
+func (s *service) GetStats(ctx context.Context, in *admin.GetMetadataCacheStatsParams) (*models.MetadataCacheStats, error) {
+	log.Debugf("entered service GetStats")
+	if err := auth.Authorize(ctx, auth.AdministerMetadataCache); err != nil {
+		return nil, errors.Wrap(err, "service.GetStats")
+	}
+	stats, err := s.cache.Stats(ctx)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.GetStats")
+	}
+	return stats, nil
+}
+
+// Purge service definition
+func (s *service) Purge(ctx context.Context, in *admin.PurgeMetadataCacheParams) (*models.MetadataCachePurgeResult, error) {
+	log.Debugf("entered service Purge")
+	if err := auth.Authorize(ctx, auth.AdministerMetadataCache); err != nil {
+		return nil, errors.Wrap(err, "service.Purge")
+	}
+	var title string
+	if in.Title != nil {
+		title = *in.Title
+	}
+	expiredOnly := in.ExpiredOnly != nil && *in.ExpiredOnly
+
+	purged, err := s.cache.Purge(ctx, title, expiredOnly)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.Purge")
+	}
+	return &models.MetadataCachePurgeResult{Purged: purged}, nil
+}
@@ -0,0 +1,56 @@
+//go:build ignore
+// +build ignore
+
+// gensql writes the migration scripts of the sql directory into sql.go, so they are compiled in
+// the binary. Run it with go generate after adding or changing a script
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	names, err := filepath.Glob(filepath.Join("sql", "*.sql"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gensql.go. DO NOT EDIT.\n\n")
+	buf.WriteString("package migration\n\n")
+	buf.WriteString("// files are the migration scripts of the sql directory by file name\n")
+	buf.WriteString("var files = map[string]string{\n")
+	for _, name := range names {
+		body, err := ioutil.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&buf, "%q: %s,\n", filepath.Base(name), literal(string(body)))
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("sql.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// literal returns the script as a raw string literal, or a quoted one when it has a backquote
+func literal(body string) string {
+	if strings.Contains(body, "`") {
+		return strconv.Quote(body)
+	}
+	return "`" + body + "`"
+}
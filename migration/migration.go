@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate go run gensql.go
+
+var fileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change with the SQL applying and reverting it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down is empty for the migrations which can not be reverted
+	Down string
+}
+
+// Load returns the migrations compiled in the binary ordered by version. The scripts of the sql
+// directory, named <version>_<name>.up.sql and <version>_<name>.down.sql, are generated in sql.go
+func Load() ([]Migration, error) {
+	byVersion := make(map[int64]*Migration)
+	for name, body := range files {
+		match := fileRegexp.FindStringSubmatch(name)
+		if match == nil {
+			return nil, errors.Errorf("invalid migration file name %s", name)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version %s", name)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = body
+		} else {
+			m.Down = body
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, errors.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestFilesUpToDate fails when a script of the sql directory was added or changed without
+// running go generate
+func TestFilesUpToDate(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("sql", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(files) {
+		t.Fatalf("sql.go has %d scripts, the sql directory %d: run go generate", len(files), len(names))
+	}
+	for _, name := range names {
+		body, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files[filepath.Base(name)] != string(body) {
+			t.Errorf("%s differs from sql.go: run go generate", name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != len(files)/2 {
+		t.Fatalf("got %d migrations, want %d", len(migrations), len(files)/2)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d", i, m.Version)
+		}
+		if m.Up == "" {
+			t.Errorf("migration %d_%s misses its up script", m.Version, m.Name)
+		}
+		// the baseline adopts the tables set up by hand, reverting it would drop them
+		if (m.Down == "") != (m.Version == 1) {
+			t.Errorf("migration %d_%s has down script %q", m.Version, m.Name, m.Down)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MigrationTable tracks the applied migrations
+	MigrationTable = "public.schema_migrations"
+
+	// lockID is the postgres advisory lock key serializing the migrations of concurrently
+	// starting instances, such as Lambda cold starts
+	lockID = 4752011
+
+	createMigrationTable = `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+	version bigint NOT NULL,
+	"name" varchar(200) NOT NULL,
+	applied_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_schema_migrations PRIMARY KEY (version)
+)`
+)
+
+// ErrSchemaBehind is returned by Check when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Status is the state of a migration in the database. An applied migration is unknown
+// to this build while a newer version of the service is rolled out
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// Migrator applies and reverts the embedded migrations
+type Migrator interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Status(ctx context.Context) ([]Status, error)
+	Check(ctx context.Context) error
+}
+
+type migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// New creates a migrator of the embedded migrations for the specified DB reference
+func New(db *sqlx.DB) (Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies the pending migrations in version order, each in its own transaction
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+	for {
+		migration, err := m.step(true)
+		if err != nil {
+			return applied, err
+		}
+		if migration == nil {
+			return applied, nil
+		}
+		logrus.Infof("Applied migration %d_%s", migration.Version, migration.Name)
+		applied = append(applied, *migration)
+	}
+}
+
+// Down reverts the last steps applied migrations, latest first
+func (m *migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := []Migration{}
+	for len(reverted) < steps {
+		migration, err := m.step(false)
+		if err != nil {
+			return reverted, err
+		}
+		if migration == nil {
+			break
+		}
+		logrus.Infof("Reverted migration %d_%s", migration.Version, migration.Name)
+		reverted = append(reverted, *migration)
+	}
+	return reverted, nil
+}
+
+// step applies the first pending migration, or reverts the last applied one, in a transaction
+// holding the advisory lock. It returns nil when there is nothing left to do
+func (m *migrator) step(up bool) (*Migration, error) {
+	var done *Migration
+
+	err := sqlz.Newx(m.db).Transactional(func(tx *sqlz.Tx) error {
+		_, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID)
+		if err != nil {
+			return errors.Wrap(err, "LockQuery")
+		}
+
+		_, err = tx.Exec(createMigrationTable)
+		if err != nil {
+			return errors.Wrap(err, "CreateTableQuery")
+		}
+
+		applied := []appliedMigration{}
+		err = tx.Select("version as Version", `"name" as Name`, "applied_at as AppliedAt").
+			From(MigrationTable).
+			OrderBy(sqlz.Asc("version")).
+			GetAll(&applied)
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		if up {
+			done, err = m.applyNext(tx, applied)
+		} else {
+			done, err = m.revertLast(tx, applied)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "migration")
+	}
+	return done, nil
+}
+
+func (m *migrator) applyNext(tx *sqlz.Tx, applied []appliedMigration) (*Migration, error) {
+	isApplied := make(map[int64]bool)
+	for _, a := range applied {
+		isApplied[a.Version] = true
+	}
+
+	for i := range m.migrations {
+		next := m.migrations[i]
+		if isApplied[next.Version] {
+			continue
+		}
+
+		_, err := tx.Exec(next.Up)
+		if err != nil {
+			return nil, errors.Wrapf(err, "applying %d_%s", next.Version, next.Name)
+		}
+
+		_, err = tx.InsertInto(MigrationTable).
+			Columns("version", "name").
+			Values(next.Version, next.Name).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "InsertQuery")
+		}
+		return &next, nil
+	}
+	return nil, nil
+}
+
+func (m *migrator) revertLast(tx *sqlz.Tx, applied []appliedMigration) (*Migration, error) {
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	last := applied[len(applied)-1]
+
+	migration := m.find(last.Version)
+	if migration == nil {
+		return nil, errors.Errorf("applied migration %d_%s is unknown to this build", last.Version, last.Name)
+	}
+	if migration.Down == "" {
+		return nil, errors.Errorf("migration %d_%s can not be reverted", migration.Version, migration.Name)
+	}
+
+	_, err := tx.Exec(migration.Down)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reverting %d_%s", migration.Version, migration.Name)
+	}
+
+	_, err = tx.DeleteFrom(MigrationTable).
+		Where(sqlz.Eq("version", migration.Version)).
+		Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "DeleteQuery")
+	}
+	return migration, nil
+}
+
+func (m *migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// Status returns the embedded migrations, and the applied migrations unknown to this build, in version order
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	err := m.db.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", MigrationTable)
+	if err != nil {
+		return nil, errors.Wrap(err, "Status.TableQuery")
+	}
+
+	applied := []appliedMigration{}
+	if exists {
+		err = sqlz.Newx(m.db).
+			Select("version as Version", `"name" as Name`, "applied_at as AppliedAt").
+			From(MigrationTable).
+			OrderBy(sqlz.Asc("version")).
+			GetAll(&applied)
+		if err != nil {
+			return nil, errors.Wrap(err, "Status.SelectQuery")
+		}
+	}
+
+	statuses := []Status{}
+	next := 0
+	for _, migration := range m.migrations {
+		for next < len(applied) && applied[next].Version < migration.Version {
+			statuses = append(statuses, unknownStatus(applied[next]))
+			next++
+		}
+
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if next < len(applied) && applied[next].Version == migration.Version {
+			status.AppliedAt = &applied[next].AppliedAt
+			next++
+		}
+		statuses = append(statuses, status)
+	}
+	for ; next < len(applied); next++ {
+		statuses = append(statuses, unknownStatus(applied[next]))
+	}
+
+	return statuses, nil
+}
+
+func unknownStatus(applied appliedMigration) Status {
+	return Status{Version: applied.Version, Name: applied.Name, AppliedAt: &applied.AppliedAt, Unknown: true}
+}
+
+// Check returns ErrSchemaBehind when any of the embedded migrations is not applied yet
+func (m *migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := []string{}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		} else if status.Unknown {
+			logrus.Warnf("Applied migration %d is unknown to this build", status.Version)
+		}
+	}
+
+	if len(pending) > 0 {
+		return errors.Wrap(ErrSchemaBehind, fmt.Sprintf("pending migrations %v, run migrate up", pending))
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+// Code generated by gensql.go. DO NOT EDIT.
+
+package migration
+
+// files are the migration scripts of the sql directory by file name
+var files = map[string]string{
+	"0001_create_moviestbl.down.sql": ``,
+	"0001_create_moviestbl.up.sql": `-- baseline schema, IF NOT EXISTS so databases set up by hand before the migrations are adopted as is
+CREATE TABLE IF NOT EXISTS public.moviestbl (
+	title varchar(80) NULL,
+	releasedYear varchar(80) NULL,
+	rating  varchar(80) NULL,
+	createddate timestamp NULL,
+	lastmodifieddate timestamp NULL,
+	genres text NULL,
+	"Id" serial NOT NULL,
+	sfid varchar(200) NULL,
+	CONSTRAINT pk_title PRIMARY KEY ("Id")
+);
+`,
+	"0002_movie_slugs.down.sql": `DROP INDEX IF EXISTS public.ix_moviestbl_sfid;
+DROP INDEX IF EXISTS public.ux_moviestbl_slug;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS slug;
+`,
+	"0002_movie_slugs.up.sql": `-- movie slugs used by GET /movies/{id}
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS slug varchar(200) NULL;
+UPDATE public.moviestbl
+	SET slug = trim(both '-' from lower(regexp_replace(concat_ws(' ', title, releasedYear), '[^[:alnum:]]+', '-', 'g'))) || '-' || "Id"
+	WHERE slug IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_moviestbl_slug ON public.moviestbl (slug);
+CREATE INDEX IF NOT EXISTS ix_moviestbl_sfid ON public.moviestbl (sfid);
+`,
+	"0003_movie_soft_delete.down.sql": `DROP INDEX IF EXISTS public.ix_moviestbl_deleted_at;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS deleted_at;
+`,
+	"0003_movie_soft_delete.up.sql": `-- soft delete tombstone, see DELETE /movies/{id} and POST /admin/movies/purge
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS deleted_at timestamp NULL;
+CREATE INDEX IF NOT EXISTS ix_moviestbl_deleted_at ON public.moviestbl (deleted_at) WHERE deleted_at IS NOT NULL;
+`,
+	"0004_genres.down.sql": `-- restore the comma separated genres column from the normalized genres
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS genres text NULL;
+UPDATE public.moviestbl mv
+	SET genres = (
+		SELECT string_agg(g."name", ', ' ORDER BY g."name")
+		FROM public.movie_genres mg
+		JOIN public.genres g ON g.id = mg.genre_id
+		WHERE mg.movie_id = mv."Id"
+	);
+DROP TABLE IF EXISTS public.movie_genres;
+DROP TABLE IF EXISTS public.genres;
+`,
+	"0004_genres.up.sql": `-- normalized genres, replacing the comma separated moviestbl.genres column
+CREATE TABLE IF NOT EXISTS public.genres (
+	id serial NOT NULL,
+	"name" varchar(80) NOT NULL,
+	CONSTRAINT pk_genres PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ux_genres_name ON public.genres (lower("name"));
+
+CREATE TABLE IF NOT EXISTS public.movie_genres (
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	genre_id integer NOT NULL REFERENCES public.genres (id) ON DELETE CASCADE,
+	CONSTRAINT pk_movie_genres PRIMARY KEY (movie_id, genre_id)
+);
+CREATE INDEX IF NOT EXISTS ix_movie_genres_genre_id ON public.movie_genres (genre_id);
+
+-- split the existing comma lists, e.g. OMDb's "Action, Drama", into one genre each
+INSERT INTO public.genres ("name")
+	SELECT DISTINCT ON (lower(trim(g."name"))) trim(g."name")
+	FROM public.moviestbl mv
+	CROSS JOIN LATERAL unnest(string_to_array(mv.genres, ',')) AS g("name")
+	WHERE trim(g."name") <> ''
+	ON CONFLICT DO NOTHING;
+INSERT INTO public.movie_genres (movie_id, genre_id)
+	SELECT DISTINCT mv."Id", gn.id
+	FROM public.moviestbl mv
+	CROSS JOIN LATERAL unnest(string_to_array(mv.genres, ',')) AS g("name")
+	JOIN public.genres gn ON lower(gn."name") = lower(trim(g."name"))
+	ON CONFLICT DO NOTHING;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS genres;
+`,
+	"0005_genre_aliases.down.sql": `DROP TABLE IF EXISTS public.genre_aliases;
+`,
+	"0005_genre_aliases.up.sql": `-- genre aliases, resolving spelling variants such as "Sci-Fi" and "SciFi" to one canonical genre.
+-- alias_key is the lower case alias stripped of everything but letters and digits, see genre.AliasKey
+CREATE TABLE IF NOT EXISTS public.genre_aliases (
+	alias_key varchar(80) NOT NULL,
+	alias varchar(80) NOT NULL,
+	genre_id integer NOT NULL REFERENCES public.genres (id) ON DELETE CASCADE,
+	CONSTRAINT pk_genre_aliases PRIMARY KEY (alias_key)
+);
+CREATE INDEX IF NOT EXISTS ix_genre_aliases_genre_id ON public.genre_aliases (genre_id);
+
+-- every genre name is an alias of itself, variants already stored as separate genres have to be merged with POST /genres/{id}:merge
+INSERT INTO public.genre_aliases (alias_key, alias, genre_id)
+	SELECT DISTINCT ON (regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g')) regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g'), "name", id
+	FROM public.genres
+	WHERE regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g') <> ''
+	ORDER BY regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g'), id
+	ON CONFLICT DO NOTHING;
+`,
+	"0006_typed_release_columns.down.sql": `-- restore the releasedYear and rating varchar columns, a release date is kept in OMDb's "06 May 2011" format
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS releasedYear varchar(80) NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS rating varchar(80) NULL;
+UPDATE public.moviestbl
+	SET releasedYear = COALESCE(to_char(release_date, 'DD Mon YYYY'), release_year::text),
+		rating = rating_score::text;
+
+DROP INDEX IF EXISTS public.ix_moviestbl_rating_score;
+DROP INDEX IF EXISTS public.ix_moviestbl_release_year;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS metascore;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS rating_score;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS release_date;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS release_year;
+`,
+	"0006_typed_release_columns.up.sql": `-- typed release and score columns, replacing the releasedYear and rating varchar columns
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS release_year integer NULL CHECK (release_year BETWEEN 1888 AND 2200);
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS release_date date NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS rating_score numeric(3,1) NULL CHECK (rating_score BETWEEN 0 AND 10);
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS metascore smallint NULL CHECK (metascore BETWEEN 0 AND 100);
+
+-- backfill from the strings, releasedYear holds either a year or OMDb's full "06 May 2011" release date
+UPDATE public.moviestbl
+	SET release_date = to_date(trim(releasedYear), 'DD Mon YYYY')
+	WHERE release_date IS NULL AND trim(releasedYear) ~ '^\d{1,2} [A-Za-z]{3} \d{4}$';
+UPDATE public.moviestbl
+	SET release_date = trim(releasedYear)::date
+	WHERE release_date IS NULL AND trim(releasedYear) ~ '^\d{4}-\d{2}-\d{2}$';
+UPDATE public.moviestbl
+	SET release_year = COALESCE(extract(year FROM release_date)::integer, substring(releasedYear FROM '\d{4}')::integer)
+	WHERE release_year IS NULL AND substring(releasedYear FROM '\d{4}')::integer BETWEEN 1888 AND 2200;
+UPDATE public.moviestbl
+	SET rating_score = round(trim(rating)::numeric, 1)
+	WHERE rating_score IS NULL AND trim(rating) ~ '^\d+(\.\d+)?$' AND trim(rating)::numeric BETWEEN 0 AND 10;
+
+CREATE INDEX IF NOT EXISTS ix_moviestbl_release_year ON public.moviestbl (release_year);
+CREATE INDEX IF NOT EXISTS ix_moviestbl_rating_score ON public.moviestbl (rating_score);
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS releasedYear;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS rating;
+`,
+	"0007_movie_provenance.down.sql": `ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS provenance;
+`,
+	"0007_movie_provenance.up.sql": `-- the provider and fetch time of each movie field looked up with the metadata providers, keyed by field name
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS provenance jsonb NOT NULL DEFAULT '{}';
+`,
+	"0008_jobs.down.sql": `DROP TABLE IF EXISTS public.jobs;
+`,
+	"0008_jobs.up.sql": `-- background jobs, claimed by the workers with FOR UPDATE SKIP LOCKED, see the jobs package.
+-- A running job whose locked_until has passed is claimed again, a job failing max_attempts times is dead
+CREATE TABLE IF NOT EXISTS public.jobs (
+	id bigserial NOT NULL,
+	kind varchar(80) NOT NULL,
+	payload jsonb NOT NULL DEFAULT '{}',
+	status varchar(20) NOT NULL DEFAULT 'queued',
+	attempts integer NOT NULL DEFAULT 0,
+	max_attempts integer NOT NULL DEFAULT 5,
+	dedupe_key varchar(200) NULL,
+	run_at timestamp NOT NULL DEFAULT now(),
+	locked_until timestamp NULL,
+	last_error text NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_jobs PRIMARY KEY (id),
+	CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead'))
+);
+CREATE INDEX IF NOT EXISTS ix_jobs_queued ON public.jobs (run_at, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS ix_jobs_running ON public.jobs (locked_until) WHERE status = 'running';
+
+-- a job with a dedupe key is queued once until it has finished
+CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_dedupe_key ON public.jobs (dedupe_key) WHERE status IN ('queued', 'running');
+`,
+	"0009_job_progress.down.sql": `UPDATE public.jobs SET status = 'dead' WHERE status = 'canceled';
+ALTER TABLE public.jobs DROP CONSTRAINT IF EXISTS ck_jobs_status;
+ALTER TABLE public.jobs ADD CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead'));
+
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS checkpoint;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS started_at;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS cancel_requested;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS errors;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_failed;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_done;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_total;
+`,
+	"0009_job_progress.up.sql": `-- job progress, item errors, timestamps and cancellation for the /jobs/{id} resource
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_total bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_done bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_failed bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS errors text[] NOT NULL DEFAULT '{}';
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS cancel_requested boolean NOT NULL DEFAULT false;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS started_at timestamp NULL;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS finished_at timestamp NULL;
+-- the last item processed by a job with its progress counts, so a job claimed again resumes after it
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS checkpoint text NULL;
+
+ALTER TABLE public.jobs DROP CONSTRAINT IF EXISTS ck_jobs_status;
+ALTER TABLE public.jobs ADD CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead', 'canceled'));
+`,
+	"0010_job_schedules.down.sql": `DROP TABLE IF EXISTS public.job_schedules;
+`,
+	"0010_job_schedules.up.sql": `-- the next run of each periodic job. The instance moving next_run_at forward enqueues the run,
+-- so a schedule runs once per interval however many workers are running
+CREATE TABLE IF NOT EXISTS public.job_schedules (
+	name varchar(80) NOT NULL,
+	next_run_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_job_schedules PRIMARY KEY (name)
+);
+`,
+	"0011_movie_refreshed_at.down.sql": `DROP INDEX IF EXISTS public.ix_moviestbl_refreshed_at;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS refreshed_at;
+`,
+	"0011_movie_refreshed_at.up.sql": `-- the last time the movie was looked up again with the metadata providers, see the stale movie refresh
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS refreshed_at timestamp NULL;
+CREATE INDEX IF NOT EXISTS ix_moviestbl_refreshed_at ON public.moviestbl (COALESCE(refreshed_at, createddate, '2019-01-01')) WHERE deleted_at IS NULL;
+`,
+	"0012_movie_field_locks.down.sql": `ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS locked_fields;
+`,
+	"0012_movie_field_locks.up.sql": `-- the fields edited by hand, never overwritten by the metadata providers until unlocked
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS locked_fields text[] NOT NULL DEFAULT '{}';
+
+-- the fields edited by hand so far are locked
+UPDATE public.moviestbl
+	SET locked_fields = ARRAY(SELECT p.key FROM jsonb_each(provenance) p WHERE p.value->>'Provider' = 'manual' ORDER BY p.key)
+	WHERE provenance <> '{}';
+`,
+	"0013_metadata_cache.down.sql": `DROP TABLE IF EXISTS public.metadata_cache;
+`,
+	"0013_metadata_cache.up.sql": `-- the metadata provider lookups, keyed by normalized title. A null metadata is a not found answer
+CREATE TABLE IF NOT EXISTS public.metadata_cache (
+	key varchar(300) NOT NULL,
+	metadata jsonb NULL,
+	fetched_at timestamp NOT NULL DEFAULT now(),
+	expires_at timestamp NOT NULL,
+	CONSTRAINT pk_metadata_cache PRIMARY KEY (key)
+);
+CREATE INDEX IF NOT EXISTS ix_metadata_cache_expires_at ON public.metadata_cache (expires_at);
+`,
+	"0014_movie_details.down.sql": `DROP INDEX IF EXISTS public.ix_moviestbl_actors;
+DROP INDEX IF EXISTS public.ux_moviestbl_imdb_id;
+
+ALTER TABLE public.moviestbl
+	DROP COLUMN IF EXISTS plot,
+	DROP COLUMN IF EXISTS runtime_minutes,
+	DROP COLUMN IF EXISTS director,
+	DROP COLUMN IF EXISTS writers,
+	DROP COLUMN IF EXISTS actors,
+	DROP COLUMN IF EXISTS language,
+	DROP COLUMN IF EXISTS country,
+	DROP COLUMN IF EXISTS poster_url,
+	DROP COLUMN IF EXISTS imdb_id;
+`,
+	"0014_movie_details.up.sql": `-- the descriptive movie details returned by the metadata providers
+ALTER TABLE public.moviestbl
+	ADD COLUMN IF NOT EXISTS plot text,
+	ADD COLUMN IF NOT EXISTS runtime_minutes integer,
+	ADD COLUMN IF NOT EXISTS director text,
+	ADD COLUMN IF NOT EXISTS writers text[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS actors text[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS language text,
+	ADD COLUMN IF NOT EXISTS country text,
+	ADD COLUMN IF NOT EXISTS poster_url text,
+	ADD COLUMN IF NOT EXISTS imdb_id text;
+
+-- an IMDb ID identifies a single movie
+CREATE UNIQUE INDEX IF NOT EXISTS ux_moviestbl_imdb_id ON public.moviestbl (imdb_id) WHERE imdb_id IS NOT NULL;
+
+-- the actor filter looks up the array elements
+CREATE INDEX IF NOT EXISTS ix_moviestbl_actors ON public.moviestbl USING gin (actors);
+`,
+	"0015_api_keys.down.sql": `DROP TABLE IF EXISTS public.api_keys;
+`,
+	"0015_api_keys.up.sql": `-- the API keys of the service clients, only the SHA-256 hash of a key is stored
+CREATE TABLE IF NOT EXISTS public.api_keys (
+	id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	key_hash text NOT NULL,
+	scopes text[] NOT NULL DEFAULT '{}',
+	created_by text NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL DEFAULT now(),
+	rotated_at timestamp NULL,
+	last_used_at timestamp NULL,
+	revoked_at timestamp NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_api_keys_key_hash ON public.api_keys (key_hash);
+`,
+	"0016_watchlists.down.sql": `DROP TABLE IF EXISTS public.watchlist_entries;
+`,
+	"0016_watchlists.up.sql": `-- the watchlists of the users, keyed by the subject of their tokens
+CREATE TABLE IF NOT EXISTS public.watchlist_entries (
+	subject text NOT NULL,
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	status text NOT NULL DEFAULT 'planned' CHECK (status IN ('planned', 'watching', 'watched')),
+	priority integer NOT NULL DEFAULT 0,
+	notes text NULL,
+	added_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_watchlist_entries PRIMARY KEY (subject, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS ix_watchlist_entries_movie_id ON public.watchlist_entries (movie_id);
+`,
+	"0017_movie_reviews.down.sql": `ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS community_rating_count;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS community_rating;
+DROP TABLE IF EXISTS public.movie_reviews;
+`,
+	"0017_movie_reviews.up.sql": `-- the reviews of the users, one per user and movie, and the community rating they add up to
+CREATE TABLE IF NOT EXISTS public.movie_reviews (
+	id bigserial PRIMARY KEY,
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	subject text NOT NULL,
+	score smallint NOT NULL CHECK (score BETWEEN 1 AND 10),
+	body text NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_movie_reviews_movie_id_subject ON public.movie_reviews (movie_id, subject);
+
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS community_rating numeric(4,2) NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS community_rating_count integer NOT NULL DEFAULT 0;
+`,
+}
@@ -1,4 +1,5 @@
-CREATE TABLE public.moviestbl (
+-- baseline schema, IF NOT EXISTS so databases set up by hand before the migrations are adopted as is
+CREATE TABLE IF NOT EXISTS public.moviestbl (
 	title varchar(80) NULL,
 	releasedYear varchar(80) NULL,
 	rating  varchar(80) NULL,
@@ -8,4 +9,4 @@ CREATE TABLE public.moviestbl (
 	"Id" serial NOT NULL,
 	sfid varchar(200) NULL,
 	CONSTRAINT pk_title PRIMARY KEY ("Id")
-);
\ No newline at end of file
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.ix_moviestbl_sfid;
+DROP INDEX IF EXISTS public.ux_moviestbl_slug;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS slug;
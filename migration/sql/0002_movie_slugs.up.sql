@@ -0,0 +1,7 @@
+-- movie slugs used by GET /movies/{id}
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS slug varchar(200) NULL;
+UPDATE public.moviestbl
+	SET slug = trim(both '-' from lower(regexp_replace(concat_ws(' ', title, releasedYear), '[^[:alnum:]]+', '-', 'g'))) || '-' || "Id"
+	WHERE slug IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_moviestbl_slug ON public.moviestbl (slug);
+CREATE INDEX IF NOT EXISTS ix_moviestbl_sfid ON public.moviestbl (sfid);
@@ -0,0 +1,3 @@
+-- soft delete tombstone, see DELETE /movies/{id} and POST /admin/movies/purge
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS deleted_at timestamp NULL;
+CREATE INDEX IF NOT EXISTS ix_moviestbl_deleted_at ON public.moviestbl (deleted_at) WHERE deleted_at IS NOT NULL;
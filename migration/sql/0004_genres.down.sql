@@ -0,0 +1,11 @@
+-- restore the comma separated genres column from the normalized genres
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS genres text NULL;
+UPDATE public.moviestbl mv
+	SET genres = (
+		SELECT string_agg(g."name", ', ' ORDER BY g."name")
+		FROM public.movie_genres mg
+		JOIN public.genres g ON g.id = mg.genre_id
+		WHERE mg.movie_id = mv."Id"
+	);
+DROP TABLE IF EXISTS public.movie_genres;
+DROP TABLE IF EXISTS public.genres;
@@ -0,0 +1,29 @@
+-- normalized genres, replacing the comma separated moviestbl.genres column
+CREATE TABLE IF NOT EXISTS public.genres (
+	id serial NOT NULL,
+	"name" varchar(80) NOT NULL,
+	CONSTRAINT pk_genres PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ux_genres_name ON public.genres (lower("name"));
+
+CREATE TABLE IF NOT EXISTS public.movie_genres (
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	genre_id integer NOT NULL REFERENCES public.genres (id) ON DELETE CASCADE,
+	CONSTRAINT pk_movie_genres PRIMARY KEY (movie_id, genre_id)
+);
+CREATE INDEX IF NOT EXISTS ix_movie_genres_genre_id ON public.movie_genres (genre_id);
+
+-- split the existing comma lists, e.g. OMDb's "Action, Drama", into one genre each
+INSERT INTO public.genres ("name")
+	SELECT DISTINCT ON (lower(trim(g."name"))) trim(g."name")
+	FROM public.moviestbl mv
+	CROSS JOIN LATERAL unnest(string_to_array(mv.genres, ',')) AS g("name")
+	WHERE trim(g."name") <> ''
+	ON CONFLICT DO NOTHING;
+INSERT INTO public.movie_genres (movie_id, genre_id)
+	SELECT DISTINCT mv."Id", gn.id
+	FROM public.moviestbl mv
+	CROSS JOIN LATERAL unnest(string_to_array(mv.genres, ',')) AS g("name")
+	JOIN public.genres gn ON lower(gn."name") = lower(trim(g."name"))
+	ON CONFLICT DO NOTHING;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS genres;
@@ -0,0 +1,17 @@
+-- genre aliases, resolving spelling variants such as "Sci-Fi" and "SciFi" to one canonical genre.
+-- alias_key is the lower case alias stripped of everything but letters and digits, see genre.AliasKey
+CREATE TABLE IF NOT EXISTS public.genre_aliases (
+	alias_key varchar(80) NOT NULL,
+	alias varchar(80) NOT NULL,
+	genre_id integer NOT NULL REFERENCES public.genres (id) ON DELETE CASCADE,
+	CONSTRAINT pk_genre_aliases PRIMARY KEY (alias_key)
+);
+CREATE INDEX IF NOT EXISTS ix_genre_aliases_genre_id ON public.genre_aliases (genre_id);
+
+-- every genre name is an alias of itself, variants already stored as separate genres have to be merged with POST /genres/{id}:merge
+INSERT INTO public.genre_aliases (alias_key, alias, genre_id)
+	SELECT DISTINCT ON (regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g')) regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g'), "name", id
+	FROM public.genres
+	WHERE regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g') <> ''
+	ORDER BY regexp_replace(lower("name"), '[^[:alnum:]]', '', 'g'), id
+	ON CONFLICT DO NOTHING;
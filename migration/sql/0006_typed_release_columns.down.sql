@@ -0,0 +1,13 @@
+-- restore the releasedYear and rating varchar columns, a release date is kept in OMDb's "06 May 2011" format
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS releasedYear varchar(80) NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS rating varchar(80) NULL;
+UPDATE public.moviestbl
+	SET releasedYear = COALESCE(to_char(release_date, 'DD Mon YYYY'), release_year::text),
+		rating = rating_score::text;
+
+DROP INDEX IF EXISTS public.ix_moviestbl_rating_score;
+DROP INDEX IF EXISTS public.ix_moviestbl_release_year;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS metascore;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS rating_score;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS release_date;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS release_year;
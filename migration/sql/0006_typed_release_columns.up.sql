@@ -0,0 +1,24 @@
+-- typed release and score columns, replacing the releasedYear and rating varchar columns
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS release_year integer NULL CHECK (release_year BETWEEN 1888 AND 2200);
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS release_date date NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS rating_score numeric(3,1) NULL CHECK (rating_score BETWEEN 0 AND 10);
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS metascore smallint NULL CHECK (metascore BETWEEN 0 AND 100);
+
+-- backfill from the strings, releasedYear holds either a year or OMDb's full "06 May 2011" release date
+UPDATE public.moviestbl
+	SET release_date = to_date(trim(releasedYear), 'DD Mon YYYY')
+	WHERE release_date IS NULL AND trim(releasedYear) ~ '^\d{1,2} [A-Za-z]{3} \d{4}$';
+UPDATE public.moviestbl
+	SET release_date = trim(releasedYear)::date
+	WHERE release_date IS NULL AND trim(releasedYear) ~ '^\d{4}-\d{2}-\d{2}$';
+UPDATE public.moviestbl
+	SET release_year = COALESCE(extract(year FROM release_date)::integer, substring(releasedYear FROM '\d{4}')::integer)
+	WHERE release_year IS NULL AND substring(releasedYear FROM '\d{4}')::integer BETWEEN 1888 AND 2200;
+UPDATE public.moviestbl
+	SET rating_score = round(trim(rating)::numeric, 1)
+	WHERE rating_score IS NULL AND trim(rating) ~ '^\d+(\.\d+)?$' AND trim(rating)::numeric BETWEEN 0 AND 10;
+
+CREATE INDEX IF NOT EXISTS ix_moviestbl_release_year ON public.moviestbl (release_year);
+CREATE INDEX IF NOT EXISTS ix_moviestbl_rating_score ON public.moviestbl (rating_score);
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS releasedYear;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS rating;
@@ -0,0 +1 @@
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS provenance;
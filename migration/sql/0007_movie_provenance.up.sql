@@ -0,0 +1,2 @@
+-- the provider and fetch time of each movie field looked up with the metadata providers, keyed by field name
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS provenance jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,23 @@
+-- background jobs, claimed by the workers with FOR UPDATE SKIP LOCKED, see the jobs package.
+-- A running job whose locked_until has passed is claimed again, a job failing max_attempts times is dead
+CREATE TABLE IF NOT EXISTS public.jobs (
+	id bigserial NOT NULL,
+	kind varchar(80) NOT NULL,
+	payload jsonb NOT NULL DEFAULT '{}',
+	status varchar(20) NOT NULL DEFAULT 'queued',
+	attempts integer NOT NULL DEFAULT 0,
+	max_attempts integer NOT NULL DEFAULT 5,
+	dedupe_key varchar(200) NULL,
+	run_at timestamp NOT NULL DEFAULT now(),
+	locked_until timestamp NULL,
+	last_error text NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_jobs PRIMARY KEY (id),
+	CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead'))
+);
+CREATE INDEX IF NOT EXISTS ix_jobs_queued ON public.jobs (run_at, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS ix_jobs_running ON public.jobs (locked_until) WHERE status = 'running';
+
+-- a job with a dedupe key is queued once until it has finished
+CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_dedupe_key ON public.jobs (dedupe_key) WHERE status IN ('queued', 'running');
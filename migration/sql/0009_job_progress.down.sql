@@ -0,0 +1,12 @@
+UPDATE public.jobs SET status = 'dead' WHERE status = 'canceled';
+ALTER TABLE public.jobs DROP CONSTRAINT IF EXISTS ck_jobs_status;
+ALTER TABLE public.jobs ADD CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead'));
+
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS checkpoint;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS started_at;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS cancel_requested;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS errors;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_failed;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_done;
+ALTER TABLE public.jobs DROP COLUMN IF EXISTS progress_total;
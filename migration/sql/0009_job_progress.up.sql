@@ -0,0 +1,13 @@
+-- job progress, item errors, timestamps and cancellation for the /jobs/{id} resource
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_total bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_done bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS progress_failed bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS errors text[] NOT NULL DEFAULT '{}';
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS cancel_requested boolean NOT NULL DEFAULT false;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS started_at timestamp NULL;
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS finished_at timestamp NULL;
+-- the last item processed by a job with its progress counts, so a job claimed again resumes after it
+ALTER TABLE public.jobs ADD COLUMN IF NOT EXISTS checkpoint text NULL;
+
+ALTER TABLE public.jobs DROP CONSTRAINT IF EXISTS ck_jobs_status;
+ALTER TABLE public.jobs ADD CONSTRAINT ck_jobs_status CHECK (status IN ('queued', 'running', 'succeeded', 'dead', 'canceled'));
@@ -0,0 +1,7 @@
+-- the next run of each periodic job. The instance moving next_run_at forward enqueues the run,
+-- so a schedule runs once per interval however many workers are running
+CREATE TABLE IF NOT EXISTS public.job_schedules (
+	name varchar(80) NOT NULL,
+	next_run_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_job_schedules PRIMARY KEY (name)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.ix_moviestbl_refreshed_at;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS refreshed_at;
@@ -0,0 +1,3 @@
+-- the last time the movie was looked up again with the metadata providers, see the stale movie refresh
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS refreshed_at timestamp NULL;
+CREATE INDEX IF NOT EXISTS ix_moviestbl_refreshed_at ON public.moviestbl (COALESCE(refreshed_at, createddate, '2019-01-01')) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+-- the fields edited by hand, never overwritten by the metadata providers until unlocked
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS locked_fields text[] NOT NULL DEFAULT '{}';
+
+-- the fields edited by hand so far are locked
+UPDATE public.moviestbl
+	SET locked_fields = ARRAY(SELECT p.key FROM jsonb_each(provenance) p WHERE p.value->>'Provider' = 'manual' ORDER BY p.key)
+	WHERE provenance <> '{}';
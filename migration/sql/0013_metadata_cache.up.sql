@@ -0,0 +1,9 @@
+-- the metadata provider lookups, keyed by normalized title. A null metadata is a not found answer
+CREATE TABLE IF NOT EXISTS public.metadata_cache (
+	key varchar(300) NOT NULL,
+	metadata jsonb NULL,
+	fetched_at timestamp NOT NULL DEFAULT now(),
+	expires_at timestamp NOT NULL,
+	CONSTRAINT pk_metadata_cache PRIMARY KEY (key)
+);
+CREATE INDEX IF NOT EXISTS ix_metadata_cache_expires_at ON public.metadata_cache (expires_at);
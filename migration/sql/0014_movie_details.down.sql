@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS public.ix_moviestbl_actors;
+DROP INDEX IF EXISTS public.ux_moviestbl_imdb_id;
+
+ALTER TABLE public.moviestbl
+	DROP COLUMN IF EXISTS plot,
+	DROP COLUMN IF EXISTS runtime_minutes,
+	DROP COLUMN IF EXISTS director,
+	DROP COLUMN IF EXISTS writers,
+	DROP COLUMN IF EXISTS actors,
+	DROP COLUMN IF EXISTS language,
+	DROP COLUMN IF EXISTS country,
+	DROP COLUMN IF EXISTS poster_url,
+	DROP COLUMN IF EXISTS imdb_id;
@@ -0,0 +1,17 @@
+-- the descriptive movie details returned by the metadata providers
+ALTER TABLE public.moviestbl
+	ADD COLUMN IF NOT EXISTS plot text,
+	ADD COLUMN IF NOT EXISTS runtime_minutes integer,
+	ADD COLUMN IF NOT EXISTS director text,
+	ADD COLUMN IF NOT EXISTS writers text[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS actors text[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS language text,
+	ADD COLUMN IF NOT EXISTS country text,
+	ADD COLUMN IF NOT EXISTS poster_url text,
+	ADD COLUMN IF NOT EXISTS imdb_id text;
+
+-- an IMDb ID identifies a single movie
+CREATE UNIQUE INDEX IF NOT EXISTS ux_moviestbl_imdb_id ON public.moviestbl (imdb_id) WHERE imdb_id IS NOT NULL;
+
+-- the actor filter looks up the array elements
+CREATE INDEX IF NOT EXISTS ix_moviestbl_actors ON public.moviestbl USING gin (actors);
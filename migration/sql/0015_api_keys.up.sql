@@ -0,0 +1,15 @@
+-- the API keys of the service clients, only the SHA-256 hash of a key is stored
+CREATE TABLE IF NOT EXISTS public.api_keys (
+	id bigserial PRIMARY KEY,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	key_hash text NOT NULL,
+	scopes text[] NOT NULL DEFAULT '{}',
+	created_by text NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL DEFAULT now(),
+	rotated_at timestamp NULL,
+	last_used_at timestamp NULL,
+	revoked_at timestamp NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_api_keys_key_hash ON public.api_keys (key_hash);
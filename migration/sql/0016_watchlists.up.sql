@@ -0,0 +1,13 @@
+-- the watchlists of the users, keyed by the subject of their tokens
+CREATE TABLE IF NOT EXISTS public.watchlist_entries (
+	subject text NOT NULL,
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	status text NOT NULL DEFAULT 'planned' CHECK (status IN ('planned', 'watching', 'watched')),
+	priority integer NOT NULL DEFAULT 0,
+	notes text NULL,
+	added_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT pk_watchlist_entries PRIMARY KEY (subject, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS ix_watchlist_entries_movie_id ON public.watchlist_entries (movie_id);
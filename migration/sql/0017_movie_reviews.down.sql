@@ -0,0 +1,3 @@
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS community_rating_count;
+ALTER TABLE public.moviestbl DROP COLUMN IF EXISTS community_rating;
+DROP TABLE IF EXISTS public.movie_reviews;
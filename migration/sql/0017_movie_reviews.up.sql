@@ -0,0 +1,15 @@
+-- the reviews of the users, one per user and movie, and the community rating they add up to
+CREATE TABLE IF NOT EXISTS public.movie_reviews (
+	id bigserial PRIMARY KEY,
+	movie_id integer NOT NULL REFERENCES public.moviestbl ("Id") ON DELETE CASCADE,
+	subject text NOT NULL,
+	score smallint NOT NULL CHECK (score BETWEEN 1 AND 10),
+	body text NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_movie_reviews_movie_id_subject ON public.movie_reviews (movie_id, subject);
+
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS community_rating numeric(4,2) NULL;
+ALTER TABLE public.moviestbl ADD COLUMN IF NOT EXISTS community_rating_count integer NOT NULL DEFAULT 0;
@@ -0,0 +1,49 @@
+package movie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+// pageCursor is the decoded form of the opaque cursor parameter. It holds the sort
+// key values of the last movie of the previous page, and the sort order they belong to
+type pageCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// encodeCursor returns the cursor of the page following the specified movie
+func encodeCursor(keys []sortKey, last *SQLMovies) string {
+	c := pageCursor{Sort: sortSignature(keys)}
+	for _, key := range keys {
+		c.Values = append(c.Values, key.value(last))
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the sort key values of the cursor, which must have been issued for
+// the same sort order
+func decodeCursor(keys []sortKey, cursor string) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "malformed cursor")
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "malformed cursor")
+	}
+
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return nil, errors.Wrap(errs.ErrInvalid, "cursor does not match the orderBy and sortDir parameters")
+	}
+	return c.Values, nil
+}
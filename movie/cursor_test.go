@@ -0,0 +1,207 @@
+package movie
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderBy   *string
+		sortDir   *string
+		signature string
+		wantErr   bool
+	}{
+		{name: "default", signature: "id:asc"},
+		{name: "tiebreaker alone follows sortDir", sortDir: swag.String("DESC"), signature: "id:desc"},
+		{name: "blank orderBy", orderBy: swag.String("  "), sortDir: swag.String("desc"), signature: "id:desc"},
+		{name: "sortDir of the fields without suffix", orderBy: swag.String("rating:asc, title"), sortDir: swag.String("desc"), signature: "rating:asc,title:desc,id:asc"},
+		{name: "suffixes", orderBy: swag.String("Rating:DESC,title"), signature: "rating:desc,title:asc,id:asc"},
+		{name: "unknown field", orderBy: swag.String("plot"), wantErr: true},
+		{name: "duplicate field", orderBy: swag.String("title,Title:desc"), wantErr: true},
+		{name: "unknown direction", orderBy: swag.String("title:up"), wantErr: true},
+		{name: "unknown sortDir", sortDir: swag.String("down"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseSort(tt.orderBy, tt.sortDir)
+			if tt.wantErr {
+				if errors.Cause(err) != errs.ErrInvalid {
+					t.Fatalf("got %v, want errs.ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortSignature(keys); got != tt.signature {
+				t.Errorf("got %s, want %s", got, tt.signature)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys, err := parseSort(swag.String("rating:desc,releaseDate,metascore,createdAt"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Date(2020, time.March, 1, 10, 30, 0, 123000000, time.UTC)
+	last := &SQLMovies{
+		RatingScore: sql.NullFloat64{Float64: 7.5, Valid: true},
+		ReleaseDate: pq.NullTime{Time: time.Date(2011, time.May, 6, 0, 0, 0, 0, time.UTC), Valid: true},
+		CreatedAt:   strfmt.DateTime(createdAt),
+		Serial:      sql.NullInt64{Int64: 42, Valid: true},
+	}
+
+	values, err := decodeCursor(keys, encodeCursor(keys, last))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the NULL metascore is the fallback of its sort expression
+	want := []string{"7.5", "2011-05-06", "-1", "2020-03-01T10:30:00.123Z", "42"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("got %v, want %v", values, want)
+	}
+}
+
+func TestCursorTiebreaker(t *testing.T) {
+	keys, err := parseSort(swag.String("title"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &SQLMovies{Title: sql.NullString{String: "Thor", Valid: true}, Serial: sql.NullInt64{Int64: 7, Valid: true}}
+	second := &SQLMovies{Title: sql.NullString{String: "Thor", Valid: true}, Serial: sql.NullInt64{Int64: 9, Valid: true}}
+
+	// movies with the same title are told apart by their serial ID
+	firstCursor, secondCursor := encodeCursor(keys, first), encodeCursor(keys, second)
+	if firstCursor == secondCursor {
+		t.Fatal("movies with equal sort keys got the same cursor")
+	}
+	values, err := decodeCursor(keys, firstCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []string{"Thor", "7"}) {
+		t.Errorf("got %v, want [Thor 7]", values)
+	}
+
+	// the next page starts after the serial ID among the movies of the same title
+	sql, args := afterCondition(keys, values).Parse()
+	wantSQL := `((COALESCE(mv.title, '') > ?) OR (COALESCE(mv.title, '') = ? AND mv."Id" > ?))`
+	if sql != wantSQL {
+		t.Errorf("got %s, want %s", sql, wantSQL)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"Thor", "Thor", "7"}) {
+		t.Errorf("got args %v", args)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	keys, err := parseSort(swag.String("title"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := encodeCursor(keys, &SQLMovies{Title: sql.NullString{String: "Thor", Valid: true}})
+	encode := func(c interface{}) string {
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	otherKeys, err := parseSort(swag.String("title:desc"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"s":"title:asc,id:asc","v":["a","1"]}`))},
+		{name: "not JSON", cursor: base64.RawURLEncoding.EncodeToString([]byte("title=Thor"))},
+		{name: "truncated", cursor: valid[:len(valid)-4]},
+		{name: "tampered sort", cursor: encode(pageCursor{Sort: "title:asc", Values: []string{"Thor"}})},
+		{name: "other sort order", cursor: encodeCursor(otherKeys, &SQLMovies{})},
+		{name: "missing value", cursor: encode(pageCursor{Sort: "title:asc,id:asc", Values: []string{"Thor"}})},
+		{name: "extra value", cursor: encode(pageCursor{Sort: "title:asc,id:asc", Values: []string{"Thor", "1", "2"}})},
+		{name: "values of another type", cursor: encode(map[string]interface{}{"s": "title:asc,id:asc", "v": []int{1, 2}})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := decodeCursor(keys, tt.cursor)
+			if errors.Cause(err) != errs.ErrInvalid {
+				t.Errorf("got %v, %v, want errs.ErrInvalid", values, err)
+			}
+		})
+	}
+}
+
+func TestAfterCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy *string
+		sortDir *string
+		values  []string
+		sql     string
+	}{
+		{
+			name:   "tiebreaker ascending",
+			values: []string{"42"},
+			sql:    `((mv."Id" > ?))`,
+		},
+		{
+			name:    "tiebreaker descending",
+			sortDir: swag.String("desc"),
+			values:  []string{"42"},
+			sql:     `((mv."Id" < ?))`,
+		},
+		{
+			name:    "mixed directions",
+			orderBy: swag.String("rating:desc,year"),
+			values:  []string{"7.5", "2011", "42"},
+			sql: `((COALESCE(mv.rating_score, -1) < ?) OR ` +
+				`(COALESCE(mv.rating_score, -1) = ? AND COALESCE(mv.release_year, 0) > ?) OR ` +
+				`(COALESCE(mv.rating_score, -1) = ? AND COALESCE(mv.release_year, 0) = ? AND mv."Id" > ?))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseSort(tt.orderBy, tt.sortDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args := afterCondition(keys, tt.values).Parse()
+			if sql != tt.sql {
+				t.Errorf("got %s\nwant %s", sql, tt.sql)
+			}
+
+			// each alternative compares the values of the keys up to its own
+			want := []interface{}{}
+			for i := range tt.values {
+				for _, value := range tt.values[:i+1] {
+					want = append(want, value)
+				}
+			}
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("got args %v, want %v", args, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package movie
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/movie"
+	"github.com/movieManagement/provider"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	enrichWait = "wait"
+
+	enrichmentQueued      = "queued"
+	enrichmentStored      = "stored"
+	enrichmentNotFound    = "not-found"
+	enrichmentUnavailable = "unavailable"
+)
+
+// errNoMetadataProvider is returned by the enrichment when no metadata provider is configured
+var errNoMetadataProvider = errors.New("no metadata provider configured")
+
+// enrich looks up a movie missing from the database with the metadata provider and stores it.
+// It returns errs.ErrNotFound when the provider does not know the movie, and nil when the movie
+// exists already but is soft deleted
+func (s *service) enrich(ctx context.Context, title string) (*models.Movie, error) {
+	if s.metadata == nil {
+		return nil, errNoMetadataProvider
+	}
+
+	// the movie may have been stored since the search, e.g. by an earlier lookup
+	if existing, err := s.existing(ctx, title); existing != nil || err != nil {
+		return visible(existing), err
+	}
+
+	metadata, err := s.metadata.MovieByTitle(ctx, title)
+	if err != nil {
+		return nil, errors.Wrap(err, s.metadata.Name())
+	}
+
+	// the provider may know the movie by a title spelled differently than the searched one
+	if existing, err := s.existing(ctx, metadata.Title); existing != nil || err != nil {
+		return visible(existing), err
+	}
+	if existing, err := s.existingImdbID(ctx, metadata.ImdbID); existing != nil || err != nil {
+		return visible(existing), err
+	}
+
+	in, provenance := createMovie(metadata)
+	return s.repo.CreateMovie(ctx, &movie.CreateMovieParams{Movie: in}, provenance)
+}
+
+// refresh looks up the movie again with the metadata provider and updates the fields it supplies.
+// The title is kept, it identifies the movie, and so are the locked fields
+func (s *service) refresh(ctx context.Context, id string) (*models.Movie, error) {
+	if s.metadata == nil {
+		return nil, errNoMetadataProvider
+	}
+
+	current, err := s.repo.GetMovie(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing to look up when every field is locked
+	editable := false
+	for _, field := range refreshedFields {
+		editable = editable || !isLocked(current, field)
+	}
+	if !editable {
+		return current, s.repo.MarkRefreshed(ctx, id)
+	}
+
+	// a cached lookup may be as old as the values refreshed, and other films may have the title
+	lookup := provider.WithIdentity(provider.Fresh(ctx), provider.Identity{Year: current.ReleasedYear, ImdbID: current.ImdbID})
+	metadata, err := s.metadata.MovieByTitle(lookup, current.Title)
+	if errors.Cause(err) == errs.ErrNotFound {
+		return current, s.repo.MarkRefreshed(ctx, id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, s.metadata.Name())
+	}
+
+	// the repository leaves the locked fields as they are, including the ones locked since the lookup
+	in, provenance := createMovie(metadata)
+	delete(provenance, provider.FieldTitle)
+	patch := metadataPatch(in)
+	// the IMDb ID identifies the movie too, it is only filled when missing
+	if current.ImdbID != "" {
+		delete(patch, provider.FieldImdbID)
+		delete(provenance, provider.FieldImdbID)
+	}
+	if len(patch) == 0 {
+		return current, s.repo.MarkRefreshed(ctx, id)
+	}
+	return s.repo.RefreshMovie(ctx, id, patch, provenance)
+}
+
+// refreshedFields are the fields a refresh may update
+var refreshedFields = []string{
+	provider.FieldReleasedYear, provider.FieldGenres, provider.FieldRating, provider.FieldMetascore,
+	provider.FieldPlot, provider.FieldRuntime, provider.FieldDirector, provider.FieldWriters, provider.FieldActors,
+	provider.FieldLanguage, provider.FieldCountry, provider.FieldPoster, provider.FieldImdbID,
+}
+
+// existing returns the movie with the title, including the soft deleted ones, or nil
+func (s *service) existing(ctx context.Context, title string) (*models.Movie, error) {
+	if title == "" {
+		return nil, nil
+	}
+	existing, err := s.repo.GetMovieByTitle(ctx, title, true)
+	if errors.Cause(err) == errs.ErrNotFound {
+		return nil, nil
+	}
+	return existing, err
+}
+
+// existingImdbID returns the movie with the IMDb ID, including the soft deleted ones, or nil
+func (s *service) existingImdbID(ctx context.Context, imdbID string) (*models.Movie, error) {
+	if imdbID == "" {
+		return nil, nil
+	}
+	existing, err := s.repo.GetMovieByImdbID(ctx, imdbID, true)
+	if errors.Cause(err) == errs.ErrNotFound {
+		return nil, nil
+	}
+	return existing, err
+}
+
+// visible hides a soft deleted movie, which must not be looked up again
+func visible(m *models.Movie) *models.Movie {
+	if m == nil || m.DeletedAt != nil {
+		return nil
+	}
+	return m
+}
+
+// EnrichMovie looks up the movie with the metadata provider and stores it, unless it exists
+// already. It returns errs.ErrNotFound when the provider does not know the movie
+func (s *service) EnrichMovie(ctx context.Context, title string) (*models.Movie, error) {
+	logrus.Debugf("entered service EnrichMovie")
+	created, err := s.enrich(ctx, title)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.EnrichMovie")
+	}
+	return created, nil
+}
+
+// titleOnly reports whether the search is the first page of a search by title alone. The other
+// searches finding no movie are not misses the providers would fill
+func titleOnly(in *movie.SearchMoviesParams) bool {
+	if strings.TrimSpace(swag.StringValue(in.Title)) == "" {
+		return false
+	}
+	if in.ID != nil || in.Year != nil || in.Rating != nil || len(splitGenres(in.Genres)) != 0 {
+		return false
+	}
+	if strings.TrimSpace(swag.StringValue(in.DollarFilter)) != "" || swag.StringValue(in.Cursor) != "" {
+		return false
+	}
+	offset, err := strconv.Atoi(swag.StringValue(in.Offset))
+	return in.Offset == nil || (err == nil && offset == 0)
+}
+
+// searchMiss handles a search by title finding no movie. The movie is looked up in the
+// background, or with wait right away, and the enrichment status is returned
+func (s *service) searchMiss(ctx context.Context, in *movie.SearchMoviesParams) (*models.Movie, string) {
+	// the lookup is skipped while the circuit breakers of the providers are open
+	if !provider.Available(s.metadata) {
+		return nil, enrichmentUnavailable
+	}
+
+	if swag.StringValue(in.Enrich) != enrichWait {
+		if err := s.enqueuer.EnqueueEnrichment(ctx, *in.Title); err != nil {
+			logrus.Warnf("enrichment of %q not queued: %v", *in.Title, err)
+			return nil, enrichmentUnavailable
+		}
+		return nil, enrichmentQueued
+	}
+
+	created, err := s.enrich(ctx, *in.Title)
+	if errors.Cause(err) == errs.ErrNotFound {
+		return nil, enrichmentNotFound
+	}
+	if err != nil {
+		logrus.Warnf("metadata lookup of %q failed: %v", *in.Title, err)
+		return nil, enrichmentUnavailable
+	}
+	if created == nil {
+		return nil, enrichmentNotFound
+	}
+	return created, enrichmentStored
+}
+
+// sameTitle reports whether the title of the movie stored is the one searched, ignoring the case
+// and the surrounding spaces
+func sameTitle(stored, searched string) bool {
+	return strings.EqualFold(strings.TrimSpace(stored), strings.TrimSpace(searched))
+}
+
+// createMovie converts the provider metadata to a new movie, dropping the values which do not
+// validate. The provenance records the source of the values kept
+func createMovie(metadata *provider.MovieMetadata) (*models.CreateMovie, models.Provenance) {
+	in := &models.CreateMovie{}
+	provenance := models.Provenance{}
+	keep := func(field, sourceField string) {
+		if source, ok := metadata.Sources[sourceField]; ok {
+			provenance[field] = models.FieldSource{
+				Provider:  source.Provider,
+				FetchedAt: strfmt.DateTime(source.FetchedAt),
+			}
+		}
+	}
+
+	if metadata.Title != "" {
+		in.Title = metadata.Title
+		keep(provider.FieldTitle, provider.FieldTitle)
+	}
+
+	// the full release date also gives the year, the Year of a series may be a range such as "2011–2019"
+	if _, _, err := parseReleasedYear(metadata.Released); err == nil && !notAvailable(metadata.Released) {
+		in.ReleasedYear = metadata.Released
+		keep(provider.FieldReleasedYear, provider.FieldReleaseDate)
+		keep(provider.FieldReleaseDate, provider.FieldReleaseDate)
+	} else if _, _, err := parseReleasedYear(metadata.Year); err == nil && !notAvailable(metadata.Year) {
+		in.ReleasedYear = metadata.Year
+		keep(provider.FieldReleasedYear, provider.FieldReleasedYear)
+	}
+
+	if len(metadata.Genres) != 0 {
+		in.Genres = metadata.Genres
+		keep(provider.FieldGenres, provider.FieldGenres)
+	}
+	if _, err := parseRating(metadata.Rating); err == nil && !notAvailable(metadata.Rating) {
+		in.Rating = metadata.Rating
+		keep(provider.FieldRating, provider.FieldRating)
+	}
+	if _, err := parseMetascore(metadata.Metascore); err == nil && metadata.Metascore != nil {
+		in.Metascore = metadata.Metascore
+		keep(provider.FieldMetascore, provider.FieldMetascore)
+	}
+
+	if !notAvailable(metadata.Plot) {
+		in.Plot = metadata.Plot
+		keep(provider.FieldPlot, provider.FieldPlot)
+	}
+	if !notAvailable(metadata.Director) {
+		in.Director = metadata.Director
+		keep(provider.FieldDirector, provider.FieldDirector)
+	}
+	if !notAvailable(metadata.Language) {
+		in.Language = metadata.Language
+		keep(provider.FieldLanguage, provider.FieldLanguage)
+	}
+	if !notAvailable(metadata.Country) {
+		in.Country = metadata.Country
+		keep(provider.FieldCountry, provider.FieldCountry)
+	}
+	if _, err := parseRuntime(metadata.Runtime); err == nil && metadata.Runtime != nil {
+		in.Runtime = metadata.Runtime
+		keep(provider.FieldRuntime, provider.FieldRuntime)
+	}
+	if len(detailList(metadata.Writers)) != 0 {
+		in.Writers = metadata.Writers
+		keep(provider.FieldWriters, provider.FieldWriters)
+	}
+	if len(detailList(metadata.Actors)) != 0 {
+		in.Actors = metadata.Actors
+		keep(provider.FieldActors, provider.FieldActors)
+	}
+	if _, err := parsePoster(metadata.Poster); err == nil && !notAvailable(metadata.Poster) {
+		in.Poster = metadata.Poster
+		keep(provider.FieldPoster, provider.FieldPoster)
+	}
+	if _, err := parseImdbID(metadata.ImdbID); err == nil && !notAvailable(metadata.ImdbID) {
+		in.ImdbID = metadata.ImdbID
+		keep(provider.FieldImdbID, provider.FieldImdbID)
+	}
+	return in, provenance
+}
+
+// metadataPatch converts the provider values of the movie to a merge patch of the fields they
+// supply, leaving out the title. The values are typed as decoded from JSON, see patchFields
+func metadataPatch(in *models.CreateMovie) models.MoviePatch {
+	patch := models.MoviePatch{}
+	if in.ReleasedYear != "" {
+		patch[provider.FieldReleasedYear] = in.ReleasedYear
+	}
+	if len(in.Genres) != 0 {
+		patch[provider.FieldGenres] = patchValues(in.Genres)
+	}
+	if in.Rating != "" {
+		patch[provider.FieldRating] = in.Rating
+	}
+	if in.Metascore != nil {
+		patch[provider.FieldMetascore] = float64(*in.Metascore)
+	}
+	if in.Runtime != nil {
+		patch[provider.FieldRuntime] = float64(*in.Runtime)
+	}
+	if len(in.Writers) != 0 {
+		patch[provider.FieldWriters] = patchValues(in.Writers)
+	}
+	if len(in.Actors) != 0 {
+		patch[provider.FieldActors] = patchValues(in.Actors)
+	}
+	for field, value := range map[string]string{
+		provider.FieldPlot:     in.Plot,
+		provider.FieldDirector: in.Director,
+		provider.FieldLanguage: in.Language,
+		provider.FieldCountry:  in.Country,
+		provider.FieldPoster:   in.Poster,
+		provider.FieldImdbID:   in.ImdbID,
+	} {
+		if value != "" {
+			patch[field] = value
+		}
+	}
+	return patch
+}
+
+// patchValues converts the strings to an array as decoded from JSON
+func patchValues(values []string) []interface{} {
+	items := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		items = append(items, value)
+	}
+	return items
+}
@@ -0,0 +1,48 @@
+package movie
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+// ETag returns the version of the movie, derived from its last modified date/time
+func ETag(m *models.Movie) string {
+	return fmt.Sprintf("\"%d\"", toMillis(time.Time(m.LastModifiedAt)))
+}
+
+// expectedVersion returns the movie last modified date/time the client expects to update,
+// taken from the If-Match header or else from the LastModifiedAt value of the body. A nil
+// result means the client did not ask for a version check
+func expectedVersion(ifMatch *string, lastModifiedAt strfmt.DateTime) (*time.Time, error) {
+	if ifMatch != nil && *ifMatch != "" {
+		tag := strings.Trim(strings.TrimPrefix(strings.TrimSpace(*ifMatch), "W/"), "\"")
+		millis, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("malformed If-Match header %q", *ifMatch))
+		}
+		t := time.Unix(0, millis*int64(time.Millisecond))
+		return &t, nil
+	}
+
+	if t := time.Time(lastModifiedAt); !t.IsZero() {
+		return &t, nil
+	}
+
+	return nil, nil
+}
+
+// sameVersion compares two last modified date/times at the millisecond precision of the API
+func sameVersion(current strfmt.DateTime, expected time.Time) bool {
+	return toMillis(time.Time(current)) == toMillis(expected)
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
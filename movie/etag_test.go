@@ -0,0 +1,91 @@
+package movie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/pkg/errors"
+)
+
+func TestETag(t *testing.T) {
+	modified := time.Date(2020, time.March, 1, 12, 0, 0, 123456789, time.UTC)
+	m := &models.Movie{LastModifiedAt: strfmt.DateTime(modified)}
+	if got, want := ETag(m), `"1583064000123"`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// the ETag sent back in If-Match is the version of the movie
+	expected, err := expectedVersion(swag.String(ETag(m)), strfmt.DateTime{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameVersion(m.LastModifiedAt, *expected) {
+		t.Errorf("version %s of ETag %s is not the movie version %s", expected, ETag(m), modified)
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	body := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		ifMatch *string
+		body    strfmt.DateTime
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "no version"},
+		{name: "empty If-Match", ifMatch: swag.String("")},
+		{name: "strong ETag", ifMatch: swag.String(`"1583064000123"`), want: timePtr(time.Unix(1583064000, 123000000))},
+		{name: "weak ETag", ifMatch: swag.String(` W/"1583064000123" `), want: timePtr(time.Unix(1583064000, 123000000))},
+		{name: "unquoted ETag", ifMatch: swag.String("1583064000123"), want: timePtr(time.Unix(1583064000, 123000000))},
+		{name: "If-Match wins over the body", ifMatch: swag.String(`"0"`), body: strfmt.DateTime(body), want: timePtr(time.Unix(0, 0))},
+		{name: "body", body: strfmt.DateTime(body), want: &body},
+		{name: "malformed ETag", ifMatch: swag.String(`"v2"`), wantErr: true},
+		{name: "wildcard", ifMatch: swag.String("*"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expectedVersion(tt.ifMatch, tt.body)
+			if tt.wantErr {
+				if errors.Cause(err) != errs.ErrInvalid {
+					t.Fatalf("got %v, %v, want errs.ErrInvalid", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameVersion(t *testing.T) {
+	current := strfmt.DateTime(time.Date(2020, time.March, 1, 12, 0, 0, 123456789, time.UTC))
+	tests := []struct {
+		expected time.Time
+		want     bool
+	}{
+		{expected: time.Date(2020, time.March, 1, 12, 0, 0, 123456789, time.UTC), want: true},
+		{expected: time.Date(2020, time.March, 1, 12, 0, 0, 123000000, time.UTC), want: true},
+		{expected: time.Date(2020, time.March, 1, 13, 0, 0, 123000000, time.FixedZone("CET", 3600)), want: true},
+		{expected: time.Date(2020, time.March, 1, 12, 0, 0, 124000000, time.UTC)},
+		{expected: time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := sameVersion(current, tt.expected); got != tt.want {
+			t.Errorf("sameVersion(%s, %s) = %v, want %v", current, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
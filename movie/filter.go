@@ -0,0 +1,364 @@
+package movie
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/ido50/sqlz"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+type filterKind int
+
+const (
+	filterString filterKind = iota
+	filterInteger
+	filterNumber
+	filterDate
+	filterDateTime
+	// filterList matches the text arrays containing the value, only with eq and ne
+	filterList
+)
+
+// filterField describes a field that can be used in a $filter expression
+type filterField struct {
+	column string
+	kind   filterKind
+}
+
+// filterFields is the whitelist of the $filter fields, keyed by lower case field name
+var filterFields = map[string]filterField{
+	"id":             {column: "mv.sfid", kind: filterString},
+	"slug":           {column: "mv.slug", kind: filterString},
+	"title":          {column: "mv.title", kind: filterString},
+	"year":           {column: "mv.release_year", kind: filterInteger},
+	"releasedate":    {column: "mv.release_date", kind: filterDate},
+	"rating":         {column: "mv.rating_score", kind: filterNumber},
+	"metascore":      {column: "mv.metascore", kind: filterInteger},
+	"runtime":        {column: "mv.runtime_minutes", kind: filterInteger},
+	"director":       {column: "mv.director", kind: filterString},
+	"language":       {column: "mv.language", kind: filterString},
+	"country":        {column: "mv.country", kind: filterString},
+	"actor":          {column: "mv.actors", kind: filterList},
+	"imdbid":         {column: "mv.imdb_id", kind: filterString},
+	"createdat":      {column: "mv.createddate", kind: filterDateTime},
+	"lastmodifiedat": {column: "mv.lastmodifieddate", kind: filterDateTime},
+}
+
+// filterDateLayouts are the accepted date/time formats besides RFC 3339
+var filterDateLayouts = []string{"2006-01-02", "01-02-2006"}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenEnd
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	text  string
+	pos   int
+	lower string
+}
+
+// filterParser is a recursive descent parser for the $filter query language:
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "(" expr ")" | comparison
+//	comparison = field ( "eq" | "ne" | "gt" | "ge" | "lt" | "le" ) value
+//
+// A value is either quoted with single or double quotes, or the bare words up
+// to the next "and", "or", ")" or the end of the expression. The keyword null
+// compares with NULL, e.g. rating eq null
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+// parseFilter converts the $filter expression into a where condition. Syntax errors and
+// unknown fields are reported as errs.ErrInvalid with the (1-based) position of the error
+func parseFilter(filter string) (sqlz.WhereCondition, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, filterError(tok.pos, "unexpected %q, expected and/or", tok.text)
+	}
+	return cond, nil
+}
+
+func filterError(pos int, format string, args ...interface{}) error {
+	return errors.Wrap(errs.ErrInvalid, fmt.Sprintf("$filter %s at position %d", fmt.Sprintf(format, args...), pos))
+}
+
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "(", pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")", pos: i + 1})
+			i++
+		case r == '\'' || r == '"':
+			// quoted value, the quote character is escaped by doubling it
+			start := i
+			var b strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						b.WriteRune(r)
+						i++
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, filterError(start+1, "unterminated string")
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: b.String(), pos: start + 1})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				i++
+			}
+			text := string(runes[start:i])
+			tokens = append(tokens, filterToken{kind: tokenWord, text: text, lower: strings.ToLower(text), pos: start + 1})
+		}
+	}
+
+	tokens = append(tokens, filterToken{kind: tokenEnd, text: "end of filter", pos: len(runes) + 1})
+	return tokens, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) take() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEnd {
+		p.next++
+	}
+	return tok
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && tok.lower == keyword
+}
+
+func (p *filterParser) parseExpr() (sqlz.WhereCondition, error) {
+	conds := []sqlz.WhereCondition{}
+	for {
+		cond, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+
+		if !p.isKeyword("or") {
+			break
+		}
+		p.take()
+	}
+
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return sqlz.Or(conds...), nil
+}
+
+func (p *filterParser) parseTerm() (sqlz.WhereCondition, error) {
+	conds := []sqlz.WhereCondition{}
+	for {
+		cond, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+
+		if !p.isKeyword("and") {
+			break
+		}
+		p.take()
+	}
+
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return sqlz.And(conds...), nil
+}
+
+func (p *filterParser) parseFactor() (sqlz.WhereCondition, error) {
+	if p.peek().kind != tokenOpen {
+		return p.parseComparison()
+	}
+
+	p.take()
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.take(); tok.kind != tokenClose {
+		return nil, filterError(tok.pos, "unexpected %q, expected )", tok.text)
+	}
+	return cond, nil
+}
+
+func (p *filterParser) parseComparison() (sqlz.WhereCondition, error) {
+	fieldTok := p.take()
+	if fieldTok.kind != tokenWord {
+		return nil, filterError(fieldTok.pos, "unexpected %q, expected a field name", fieldTok.text)
+	}
+	field, ok := filterFields[fieldTok.lower]
+	if !ok {
+		return nil, filterError(fieldTok.pos, "unknown field %q", fieldTok.text)
+	}
+
+	opTok := p.take()
+	if opTok.kind != tokenWord {
+		return nil, filterError(opTok.pos, "unexpected %q, expected eq, ne, gt, ge, lt or le", opTok.text)
+	}
+	op := opTok.lower
+	switch op {
+	case "eq", "ne", "gt", "ge", "lt", "le":
+	default:
+		return nil, filterError(opTok.pos, "unknown operator %q", opTok.text)
+	}
+
+	valuePos := p.peek().pos
+	raw, quoted, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if field.kind == filterList {
+		return field.listComparison(opTok, op, raw, quoted)
+	}
+
+	if !quoted && strings.ToLower(raw) == "null" {
+		switch op {
+		case "eq":
+			return sqlz.IsNull(field.column), nil
+		case "ne":
+			return sqlz.IsNotNull(field.column), nil
+		default:
+			return nil, filterError(opTok.pos, "operator %s can not be used with null", op)
+		}
+	}
+
+	value, err := field.convert(raw)
+	if err != nil {
+		return nil, filterError(valuePos, "invalid value %q for %s", raw, fieldTok.text)
+	}
+
+	switch op {
+	case "ne":
+		return sqlz.Ne(field.column, value), nil
+	case "gt":
+		return sqlz.Gt(field.column, value), nil
+	case "ge":
+		return sqlz.Gte(field.column, value), nil
+	case "lt":
+		return sqlz.Lt(field.column, value), nil
+	case "le":
+		return sqlz.Lte(field.column, value), nil
+	default:
+		return sqlz.Eq(field.column, value), nil
+	}
+}
+
+// listComparison matches the arrays containing the value, null matches the empty arrays
+func (field filterField) listComparison(opTok filterToken, op, raw string, quoted bool) (sqlz.WhereCondition, error) {
+	if op != "eq" && op != "ne" {
+		return nil, filterError(opTok.pos, "operator %s can not be used with a list", op)
+	}
+
+	// containment, unlike = ANY, is looked up in the GIN index of the array
+	cond := fmt.Sprintf("%s @> ARRAY[?]::text[]", field.column)
+	args := []interface{}{raw}
+	if !quoted && strings.ToLower(raw) == "null" {
+		cond = fmt.Sprintf("cardinality(%s) = 0", field.column)
+		args = nil
+	}
+	if op == "ne" {
+		cond = "NOT (" + cond + ")"
+	}
+	return sqlz.SQLCond(cond, args...), nil
+}
+
+// parseValue returns a quoted value, or the bare words up to the next logical operator
+func (p *filterParser) parseValue() (string, bool, error) {
+	tok := p.peek()
+	if tok.kind == tokenString {
+		p.take()
+		return tok.text, true, nil
+	}
+
+	words := []string{}
+	for p.peek().kind == tokenWord && !p.isKeyword("and") && !p.isKeyword("or") {
+		words = append(words, p.take().text)
+	}
+	if len(words) == 0 {
+		return "", false, filterError(tok.pos, "unexpected %q, expected a value", tok.text)
+	}
+	return strings.Join(words, " "), false, nil
+}
+
+func (field filterField) convert(raw string) (interface{}, error) {
+	switch field.kind {
+	case filterInteger:
+		return strconv.ParseInt(raw, 10, 64)
+	case filterNumber:
+		return strconv.ParseFloat(raw, 64)
+	case filterDate:
+		for _, layout := range filterDateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.Format(releaseDateFormat), nil
+			}
+		}
+		return nil, errors.Errorf("invalid date %s", raw)
+	case filterDateTime:
+		if dt, err := strfmt.ParseDateTime(raw); err == nil {
+			return time.Time(dt), nil
+		}
+		for _, layout := range filterDateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t, nil
+			}
+		}
+		return nil, errors.Errorf("invalid date/time %s", raw)
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,268 @@
+package movie
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		sql    string
+		args   []interface{}
+	}{
+		{
+			name:   "and binds tighter than or",
+			filter: "title eq 'a' or title eq 'b' and year gt 2000",
+			sql:    "(mv.title = ? OR (mv.title = ? AND mv.release_year > ?))",
+			args:   []interface{}{"a", "b", int64(2000)},
+		},
+		{
+			name:   "and binds tighter than or on the left",
+			filter: "title eq 'a' and year gt 2000 or title eq 'b'",
+			sql:    "((mv.title = ? AND mv.release_year > ?) OR mv.title = ?)",
+			args:   []interface{}{"a", int64(2000), "b"},
+		},
+		{
+			name:   "parentheses group the or",
+			filter: "(title eq 'a' or title eq 'b') and year gt 2000",
+			sql:    "((mv.title = ? OR mv.title = ?) AND mv.release_year > ?)",
+			args:   []interface{}{"a", "b", int64(2000)},
+		},
+		{
+			name:   "nested parentheses",
+			filter: "rating eq 7.5 and ((metascore ge 60) or runtime le 90)",
+			sql:    "(mv.rating_score = ? AND (mv.metascore >= ? OR mv.runtime_minutes <= ?))",
+			args:   []interface{}{7.5, int64(60), int64(90)},
+		},
+		{
+			name:   "keywords, fields and operators ignore the case",
+			filter: "YEAR Ge 1999 AND Title NE 'x'",
+			sql:    "(mv.release_year >= ? AND mv.title <> ?)",
+			args:   []interface{}{int64(1999), "x"},
+		},
+		{
+			name:   "doubled single quote",
+			filter: "title eq 'It''s'",
+			sql:    "mv.title = ?",
+			args:   []interface{}{"It's"},
+		},
+		{
+			name:   "doubled double quote",
+			filter: `title eq "say ""hi"""`,
+			sql:    "mv.title = ?",
+			args:   []interface{}{`say "hi"`},
+		},
+		{
+			name:   "other quote inside a string",
+			filter: `director eq "O'Brien"`,
+			sql:    "mv.director = ?",
+			args:   []interface{}{"O'Brien"},
+		},
+		{
+			name:   "quoted keywords are values",
+			filter: "title eq 'War and Peace' or title eq '(or)'",
+			sql:    "(mv.title = ? OR mv.title = ?)",
+			args:   []interface{}{"War and Peace", "(or)"},
+		},
+		{
+			name:   "bare words up to the keyword",
+			filter: "title eq The Matrix and year eq 1999",
+			sql:    "(mv.title = ? AND mv.release_year = ?)",
+			args:   []interface{}{"The Matrix", int64(1999)},
+		},
+		{
+			name:   "eq null",
+			filter: "rating eq null",
+			sql:    "mv.rating_score IS NULL",
+		},
+		{
+			name:   "ne null",
+			filter: "releaseDate ne NULL",
+			sql:    "mv.release_date IS NOT NULL",
+		},
+		{
+			name:   "quoted null is a value",
+			filter: "title eq 'null'",
+			sql:    "mv.title = ?",
+			args:   []interface{}{"null"},
+		},
+		{
+			name:   "list containment",
+			filter: "actor eq 'Keanu Reeves'",
+			sql:    "mv.actors @> ARRAY[?]::text[]",
+			args:   []interface{}{"Keanu Reeves"},
+		},
+		{
+			name:   "list not containing",
+			filter: "actor ne Keanu Reeves",
+			sql:    "NOT (mv.actors @> ARRAY[?]::text[])",
+			args:   []interface{}{"Keanu Reeves"},
+		},
+		{
+			name:   "empty list",
+			filter: "actor eq null",
+			sql:    "cardinality(mv.actors) = 0",
+		},
+		{
+			name:   "non empty list",
+			filter: "actor ne null",
+			sql:    "NOT (cardinality(mv.actors) = 0)",
+		},
+		{
+			name:   "list containing the quoted null",
+			filter: "actor eq 'null'",
+			sql:    "mv.actors @> ARRAY[?]::text[]",
+			args:   []interface{}{"null"},
+		},
+		{
+			name:   "date in the US layout",
+			filter: "releaseDate ge 01-31-2020",
+			sql:    "mv.release_date >= ?",
+			args:   []interface{}{"2020-01-31"},
+		},
+		{
+			name:   "date/time",
+			filter: "createdAt lt 2020-01-31T10:00:00Z",
+			sql:    "mv.createddate < ?",
+			args:   []interface{}{time.Date(2020, time.January, 31, 10, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", tt.filter, err)
+			}
+			sql, args := cond.Parse()
+			if sql != tt.sql {
+				t.Errorf("got %s, want %s", sql, tt.sql)
+			}
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("got args %#v, want %#v", args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilterNullOperators(t *testing.T) {
+	for _, op := range []string{"gt", "ge", "lt", "le"} {
+		for _, field := range []string{"rating", "actor"} {
+			filter := fmt.Sprintf("%s %s null", field, op)
+			_, err := parseFilter(filter)
+			assertFilterError(t, filter, err, len(field)+2)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		pos     int
+		message string
+	}{
+		{name: "empty", filter: "", pos: 1, message: "expected a field name"},
+		{name: "unterminated single quote", filter: "title eq 'abc", pos: 10, message: "unterminated string"},
+		{name: "unterminated double quote", filter: `title eq "abc""`, pos: 10, message: "unterminated string"},
+		{name: "unterminated after a doubled quote", filter: "title eq 'It''", pos: 10, message: "unterminated string"},
+		{name: "unknown field", filter: "title eq 'a' and foo eq 1", pos: 18, message: `unknown field "foo"`},
+		{name: "operator as field", filter: "eq 1", pos: 1, message: `unknown field "eq"`},
+		{name: "unknown operator", filter: "title xx 1", pos: 7, message: `unknown operator "xx"`},
+		{name: "missing operator", filter: "title", pos: 6, message: "expected eq, ne, gt, ge, lt or le"},
+		{name: "missing value", filter: "title eq", pos: 9, message: "expected a value"},
+		{name: "keyword as value", filter: "title eq and", pos: 10, message: "expected a value"},
+		{name: "invalid integer", filter: "year eq abc", pos: 9, message: `invalid value "abc" for year`},
+		{name: "invalid number", filter: "rating gt high", pos: 11, message: `invalid value "high" for rating`},
+		{name: "invalid date", filter: "releaseDate eq 2020-13-45", pos: 16, message: `invalid value "2020-13-45"`},
+		{name: "list operator", filter: "actor lt 'x'", pos: 7, message: "can not be used with a list"},
+		{name: "unclosed parenthesis", filter: "(title eq 'a'", pos: 14, message: "expected )"},
+		{name: "extra parenthesis", filter: "title eq 'a')", pos: 13, message: "expected and/or"},
+		{name: "missing keyword", filter: "title eq 'a' title eq 'b'", pos: 14, message: "expected and/or"},
+		{name: "dangling or", filter: "title eq 'a' or", pos: 16, message: "expected a field name"},
+		{name: "position in runes", filter: "title eq 'Amélie' and x eq 1", pos: 23, message: `unknown field "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFilter(tt.filter)
+			assertFilterError(t, tt.filter, err, tt.pos)
+			if err != nil && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("got %v, want %s", err, tt.message)
+			}
+		})
+	}
+}
+
+func assertFilterError(t *testing.T, filter string, err error, pos int) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("parseFilter(%q) succeeded, want an error", filter)
+	}
+	if errors.Cause(err) != errs.ErrInvalid {
+		t.Errorf("parseFilter(%q): got %v, want errs.ErrInvalid", filter, err)
+	}
+	if want := fmt.Sprintf("at position %d", pos); !strings.Contains(err.Error(), want) {
+		t.Errorf("parseFilter(%q): got %v, want %s", filter, err, want)
+	}
+}
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		tokens []filterToken
+	}{
+		{
+			filter: "(year ge 2000)",
+			tokens: []filterToken{
+				{kind: tokenOpen, text: "(", pos: 1},
+				{kind: tokenWord, text: "year", lower: "year", pos: 2},
+				{kind: tokenWord, text: "ge", lower: "ge", pos: 7},
+				{kind: tokenWord, text: "2000", lower: "2000", pos: 10},
+				{kind: tokenClose, text: ")", pos: 14},
+				{kind: tokenEnd, text: "end of filter", pos: 15},
+			},
+		},
+		{
+			filter: `Title eq 'It''s' or "a ""b"""`,
+			tokens: []filterToken{
+				{kind: tokenWord, text: "Title", lower: "title", pos: 1},
+				{kind: tokenWord, text: "eq", lower: "eq", pos: 7},
+				{kind: tokenString, text: "It's", pos: 10},
+				{kind: tokenWord, text: "or", lower: "or", pos: 18},
+				{kind: tokenString, text: `a "b"`, pos: 21},
+				{kind: tokenEnd, text: "end of filter", pos: 30},
+			},
+		},
+		{
+			filter: "title eq ''",
+			tokens: []filterToken{
+				{kind: tokenWord, text: "title", lower: "title", pos: 1},
+				{kind: tokenWord, text: "eq", lower: "eq", pos: 7},
+				{kind: tokenString, text: "", pos: 10},
+				{kind: tokenEnd, text: "end of filter", pos: 12},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tokens, err := tokenizeFilter(tt.filter)
+		if err != nil {
+			t.Errorf("tokenizeFilter(%q): %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) {
+			t.Errorf("tokenizeFilter(%q)\ngot  %+v\nwant %+v", tt.filter, tokens, tt.tokens)
+		}
+	}
+}
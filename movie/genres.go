@@ -0,0 +1,84 @@
+package movie
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ido50/sqlz"
+	"github.com/lib/pq"
+	"github.com/movieManagement/genre"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MovieGenreTable . . .
+	MovieGenreTable = "public.movie_genres"
+
+	genresMatchAll = "all"
+)
+
+// movieGenresField returns the genre names of the movie as an array, sorted by name
+const movieGenresField = `ARRAY(SELECT g.name FROM public.movie_genres mg JOIN public.genres g ON g.id = mg.genre_id WHERE mg.movie_id = mv."Id" ORDER BY g.name) as Genres`
+
+// splitGenres splits comma separated values such as OMDb's "Action, Drama" into one genre each,
+// trimming spaces and dropping empty and duplicate (case insensitive) names
+func splitGenres(values []string) []string {
+	genres := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[strings.ToLower(name)] {
+				continue
+			}
+			seen[strings.ToLower(name)] = true
+			genres = append(genres, name)
+		}
+	}
+	return genres
+}
+
+// setMovieGenres replaces the genres of the movie. The names are resolved to their canonical
+// genre through the genre aliases, and the unknown names are created as new genres
+func setMovieGenres(tx *sqlz.Tx, movieID int64, genres []string) error {
+	_, err := tx.DeleteFrom(MovieGenreTable).
+		Where(sqlz.Eq("movie_id", movieID)).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "setMovieGenres.DeleteQuery")
+	}
+
+	for _, name := range genres {
+		id, err := genre.Resolve(tx, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.InsertInto(MovieGenreTable).
+			Columns("movie_id", "genre_id").
+			Values(movieID, id).
+			OnConflictDoNothing().
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "setMovieGenres.InsertQuery")
+		}
+	}
+	return nil
+}
+
+// genresCondition matches the movies having any, or with match "all" every one, of the genres.
+// The genres are matched through their aliases, so any spelling variant finds the canonical genre
+func genresCondition(genres []string, match string) sqlz.WhereCondition {
+	keys := []string{}
+	for _, name := range genres {
+		if key := genre.AliasKey(name); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	hasGenre := `SELECT 1 FROM public.movie_genres mg JOIN public.genre_aliases ga ON ga.genre_id = mg.genre_id WHERE mg.movie_id = mv."Id"`
+	if match == genresMatchAll {
+		return sqlz.SQLCond(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM unnest(?::text[]) k(key) WHERE NOT EXISTS (%s AND ga.alias_key = k.key))", hasGenre), pq.Array(keys))
+	}
+	return sqlz.SQLCond(fmt.Sprintf("EXISTS (%s AND ga.alias_key = ANY(?::text[]))", hasGenre), pq.Array(keys))
+}
@@ -1,31 +1,122 @@
 package movie
 
 import (
+	"github.com/go-openapi/runtime"
 	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
 	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/admin"
 	"github.com/movieManagement/gen/restapi/operations/movie"
+	"github.com/movieManagement/jobs"
 	"github.com/movieManagement/swagger"
 )
 
 // Configure configures the affiliation service
 func Configure(api *operations.MovieServiceAPI, service Service) {
-	api.MovieCreateMovieHandler = movie.CreateMovieHandlerFunc(func(params movie.CreateMovieParams) middleware.Responder {
-		result, err := service.CreateMovie(params.HTTPRequest.Context(), &params)
+	api.RegisterConsumer("application/merge-patch+json", runtime.JSONConsumer())
+
+	api.MovieCreateMovieHandler = movie.CreateMovieHandlerFunc(func(params movie.CreateMovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.CreateMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
 		if err != nil {
 			return swagger.ErrorHandler("CreateMovie :: ", err)
 		}
 		return movie.NewCreateMovieCreated().WithPayload(result)
 	})
 
-	api.MovieGetmovieHandler = movie.GetmovieHandlerFunc(func(params movie.GetmovieParams) middleware.Responder {
-		return middleware.NotImplemented("operation movies.Getmovie has not yet been implemented")
+	api.MovieGetmovieHandler = movie.GetmovieHandlerFunc(func(params movie.GetmovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.GetMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("GetMovie :: ", err)
+		}
+		return movie.NewGetmovieOK().WithETag(ETag(result)).WithPayload(result)
+	})
+
+	api.MovieUpdateMovieHandler = movie.UpdateMovieHandlerFunc(func(params movie.UpdateMovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.UpdateMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("UpdateMovie :: ", err)
+		}
+		return movie.NewUpdateMovieOK().WithETag(ETag(result)).WithPayload(result)
+	})
+
+	api.MoviePatchMovieHandler = movie.PatchMovieHandlerFunc(func(params movie.PatchMovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.PatchMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("PatchMovie :: ", err)
+		}
+		return movie.NewPatchMovieOK().WithETag(ETag(result)).WithPayload(result)
+	})
+
+	api.MovieDeleteMovieHandler = movie.DeleteMovieHandlerFunc(func(params movie.DeleteMovieParams, principal *models.Principal) middleware.Responder {
+		err := service.DeleteMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("DeleteMovie :: ", err)
+		}
+		return movie.NewDeleteMovieNoContent()
 	})
 
-	api.MovieSearchMoviesHandler = movie.SearchMoviesHandlerFunc(func(params movie.SearchMoviesParams) middleware.Responder {
-		result, err := service.SearchMovies(params.HTTPRequest.Context(), &params)
+	api.MovieRestoreMovieHandler = movie.RestoreMovieHandlerFunc(func(params movie.RestoreMovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.RestoreMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("RestoreMovie :: ", err)
+		}
+		return movie.NewRestoreMovieOK().WithPayload(result)
+	})
+
+	api.MovieUnlockMovieHandler = movie.UnlockMovieHandlerFunc(func(params movie.UnlockMovieParams, principal *models.Principal) middleware.Responder {
+		result, err := service.UnlockMovie(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("UnlockMovie :: ", err)
+		}
+		return movie.NewUnlockMovieOK().WithPayload(result)
+	})
+
+	api.MovieUnlockMovieFieldHandler = movie.UnlockMovieFieldHandlerFunc(func(params movie.UnlockMovieFieldParams, principal *models.Principal) middleware.Responder {
+		result, err := service.UnlockMovieField(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("UnlockMovieField :: ", err)
+		}
+		return movie.NewUnlockMovieFieldOK().WithPayload(result)
+	})
+
+	api.AdminPurgeMoviesHandler = admin.PurgeMoviesHandlerFunc(func(params admin.PurgeMoviesParams, principal *models.Principal) middleware.Responder {
+		result, err := service.PurgeMovies(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("PurgeMovies :: ", err)
+		}
+		return admin.NewPurgeMoviesOK().WithPayload(result)
+	})
+
+	api.MovieSearchMoviesHandler = movie.SearchMoviesHandlerFunc(func(params movie.SearchMoviesParams, principal *models.Principal) middleware.Responder {
+		result, err := service.SearchMovies(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
 		if err != nil {
 			return swagger.ErrorHandler("SearchMovies :: ", err)
 		}
 		return movie.NewSearchMoviesOK().WithPayload(result)
 	})
+
+	api.MovieImportMoviesHandler = movie.ImportMoviesHandlerFunc(func(params movie.ImportMoviesParams, principal *models.Principal) middleware.Responder {
+		result, err := service.ImportMovies(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ImportMovies :: ", err)
+		}
+		return movie.NewImportMoviesAccepted().WithLocation(jobs.Location(result.ID)).WithPayload(result)
+	})
+
+	api.MovieBulkEditMoviesHandler = movie.BulkEditMoviesHandlerFunc(func(params movie.BulkEditMoviesParams, principal *models.Principal) middleware.Responder {
+		result, err := service.BulkEditMovies(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("BulkEditMovies :: ", err)
+		}
+		return movie.NewBulkEditMoviesAccepted().WithLocation(jobs.Location(result.ID)).WithPayload(result)
+	})
+
+	api.AdminReenrichMoviesHandler = admin.ReenrichMoviesHandlerFunc(func(params admin.ReenrichMoviesParams, principal *models.Principal) middleware.Responder {
+		result, err := service.ReenrichMovies(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ReenrichMovies :: ", err)
+		}
+		return admin.NewReenrichMoviesAccepted().WithLocation(jobs.Location(result.ID)).WithPayload(result)
+	})
 }
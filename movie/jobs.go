@@ -0,0 +1,180 @@
+package movie
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/jobs"
+	"github.com/movieManagement/provider"
+	"github.com/pkg/errors"
+)
+
+// The kinds of the movie jobs
+const (
+	// EnrichJob looks up a movie missing from the database
+	EnrichJob = "movie.enrich"
+	// ImportJob imports movies by title
+	ImportJob = "movie.import"
+	// BulkEditJob patches the movies matching a filter
+	BulkEditJob = "movie.bulkEdit"
+	// ReenrichJob looks up the movies matching a filter again
+	ReenrichJob = "movie.reenrich"
+	// RefreshStaleJob looks up the movies not refreshed for a while again
+	RefreshStaleJob = "movie.refreshStale"
+)
+
+type enrichPayload struct {
+	Title string `json:"title"`
+}
+
+type importPayload struct {
+	Titles []string `json:"titles"`
+}
+
+type bulkEditPayload struct {
+	Filter string            `json:"filter"`
+	Patch  models.MoviePatch `json:"patch"`
+}
+
+type reenrichPayload struct {
+	Filter string `json:"filter"`
+}
+
+type refreshStalePayload struct {
+	MaxAgeHours int `json:"maxAgeHours"`
+	Budget      int `json:"budget"`
+}
+
+// Enqueuer schedules the background work of the movies
+type Enqueuer interface {
+	EnqueueEnrichment(ctx context.Context, title string) error
+	Enqueue(ctx context.Context, kind string, payload interface{}) (*jobs.Job, error)
+}
+
+type jobEnqueuer struct {
+	repo jobs.Repository
+}
+
+// NewJobEnqueuer creates an enqueuer queuing the work as jobs. A title already queued for
+// enrichment is not queued again
+func NewJobEnqueuer(repo jobs.Repository) Enqueuer {
+	return &jobEnqueuer{
+		repo: repo,
+	}
+}
+
+func (e *jobEnqueuer) EnqueueEnrichment(ctx context.Context, title string) error {
+	key := EnrichJob + ":" + strings.ToLower(strings.TrimSpace(title))
+	_, err := e.repo.Enqueue(ctx, EnrichJob, enrichPayload{Title: title}, jobs.DedupeKey(key))
+	return err
+}
+
+func (e *jobEnqueuer) Enqueue(ctx context.Context, kind string, payload interface{}) (*jobs.Job, error) {
+	return e.repo.Enqueue(ctx, kind, payload)
+}
+
+// RegisterJobs registers the handlers of the movie jobs with the worker. The provider lookups of
+// the jobs wait for the rate limits of the providers
+func RegisterJobs(worker *jobs.Worker, service Service) {
+	register := func(kind string, handler jobs.HandlerFunc) {
+		worker.Register(kind, func(ctx context.Context, job *jobs.Job) error {
+			return handler(provider.WaitForQuota(ctx), job)
+		})
+	}
+
+	register(EnrichJob, func(ctx context.Context, job *jobs.Job) error {
+		payload := enrichPayload{}
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+
+		_, err := service.EnrichMovie(ctx, payload.Title)
+		switch errors.Cause(err) {
+		case errs.ErrNotFound:
+			return nil
+		case errNoMetadataProvider:
+			return jobs.Permanent(err)
+		}
+		return err
+	})
+
+	register(ImportJob, func(ctx context.Context, job *jobs.Job) error {
+		payload := importPayload{}
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		return service.RunImport(ctx, payload.Titles)
+	})
+
+	register(BulkEditJob, func(ctx context.Context, job *jobs.Job) error {
+		payload := bulkEditPayload{}
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		return service.RunBulkEdit(ctx, payload.Filter, payload.Patch)
+	})
+
+	register(ReenrichJob, func(ctx context.Context, job *jobs.Job) error {
+		payload := reenrichPayload{}
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		return service.RunReenrich(ctx, payload.Filter)
+	})
+
+	register(RefreshStaleJob, func(ctx context.Context, job *jobs.Job) error {
+		payload := refreshStalePayload{}
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		err := service.RunRefreshStale(ctx, time.Duration(payload.MaxAgeHours)*time.Hour, payload.Budget)
+		if errors.Cause(err) == errNoMetadataProvider {
+			return jobs.Permanent(err)
+		}
+		return err
+	})
+}
+
+// ScheduleRefresh looks up again, every interval, up to budget movies not refreshed for longer than
+// maxAge. The budget bounds the provider lookups of each run
+func ScheduleRefresh(worker *jobs.Worker, interval, maxAge time.Duration, budget int) {
+	worker.Schedule(RefreshStaleJob, interval, refreshStalePayload{
+		MaxAgeHours: int(maxAge.Hours()),
+		Budget:      budget,
+	})
+}
+
+// forEach runs fn for every item, reporting the progress of the job with the item as checkpoint.
+// The counts go on from the ones of a previous attempt of the job, the items are the ones left
+// after its checkpoint. An item failing is reported as an error of the job, which goes on with
+// the next item. A lookup the providers do not make, rate limited or with their breakers open, is
+// not a failure of the item: the job stops and is retried from its checkpoint. It also stops when
+// the context is done, e.g. when the job is canceled
+func forEach(ctx context.Context, items []string, fn func(item string) error) error {
+	done, failed, checkpoint := jobs.Resume(ctx)
+	total := done + failed + int64(len(items))
+	jobs.ReportProgress(ctx, total, done, failed, checkpoint)
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn(item)
+		if errors.Cause(err) == provider.ErrUnavailable {
+			return errors.Wrap(err, item)
+		}
+		if err != nil {
+			failed++
+			jobs.ReportError(ctx, fmt.Sprintf("%s: %v", item, err))
+		} else {
+			done++
+		}
+		jobs.ReportProgress(ctx, total, done, failed, item)
+	}
+	return nil
+}
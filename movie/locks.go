@@ -0,0 +1,97 @@
+package movie
+
+import (
+	"sort"
+
+	"github.com/ido50/sqlz"
+	"github.com/lib/pq"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/provider"
+	"github.com/pkg/errors"
+)
+
+// fieldColumns are the columns of the lockable fields, the genres are stored in movie_genres
+var fieldColumns = map[string]string{
+	provider.FieldTitle:        "title",
+	provider.FieldReleasedYear: "release_year",
+	provider.FieldReleaseDate:  "release_date",
+	provider.FieldRating:       "rating_score",
+	provider.FieldMetascore:    "metascore",
+	provider.FieldPlot:         "plot",
+	provider.FieldRuntime:      "runtime_minutes",
+	provider.FieldDirector:     "director",
+	provider.FieldWriters:      "writers",
+	provider.FieldActors:       "actors",
+	provider.FieldLanguage:     "language",
+	provider.FieldCountry:      "country",
+	provider.FieldPoster:       "poster_url",
+	provider.FieldImdbID:       "imdb_id",
+}
+
+// recordEdit records the fields changed by hand since before as manual, and locks them
+func recordEdit(tx *sqlz.Tx, sqlMovies *SQLMovies, before *models.Movie) error {
+	provenance := manualProvenance(before, sqlMovies.toMovie())
+	if len(provenance) == 0 {
+		return nil
+	}
+	if err := setProvenance(tx, sqlMovies, provenance); err != nil {
+		return err
+	}
+
+	fields := []string(sqlMovies.LockedFields)
+	for field := range provenance {
+		fields = append(fields, field)
+	}
+	return setLockedFields(tx, sqlMovies, fields)
+}
+
+// setLockedFields replaces the locked fields of the movie
+func setLockedFields(tx *sqlz.Tx, sqlMovies *SQLMovies, fields []string) error {
+	locked := pq.StringArray(uniqueFields(fields))
+	_, err := tx.Update(MovieTable).
+		Set("locked_fields", locked).
+		Where(sqlz.Eq(`mv."Id"`, sqlMovies.Serial.Int64)).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "setLockedFields.UpdateQuery")
+	}
+	sqlMovies.LockedFields = locked
+	return nil
+}
+
+// dropLocked removes the locked fields from a provider update, returning the genres to set
+func dropLocked(locked []string, updateMap map[string]interface{}, genres *[]string, provenance models.Provenance) *[]string {
+	for _, field := range locked {
+		if column, ok := fieldColumns[field]; ok {
+			delete(updateMap, column)
+		}
+		if field == provider.FieldGenres {
+			genres = nil
+		}
+		delete(provenance, field)
+	}
+	return genres
+}
+
+// isLocked reports whether the field of the movie is locked
+func isLocked(m *models.Movie, field string) bool {
+	for _, locked := range m.LockedFields {
+		if locked == field {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueFields(fields []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			unique = append(unique, field)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -2,8 +2,12 @@ package movie
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strconv"
 
 	"github.com/go-openapi/strfmt"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 	"github.com/movieManagement/gen/models"
 )
 
@@ -13,21 +17,83 @@ type SQLMovies struct {
 	Title          sql.NullString  `json:"Title,omitempty"`
 	LastModifiedAt strfmt.DateTime `json:"LastModifiedAt,omitempty"`
 	CreatedAt      strfmt.DateTime `json:"CreatedAt,omitempty"`
-	Genres         sql.NullString  `json:"Genres,omitempty"`
-	Rating         sql.NullString  `json:"Rating,omitempty"`
-	ReleasedYear   sql.NullString  `json:"ReleasedYear,omitempty"`
+	Genres         pq.StringArray  `json:"Genres,omitempty"`
+	RatingScore    sql.NullFloat64 `json:"RatingScore,omitempty"`
+	CommunityScore sql.NullFloat64 `json:"CommunityScore,omitempty"`
+	CommunityCount int64           `json:"CommunityCount,omitempty"`
+	ReleaseYear    sql.NullInt64   `json:"ReleaseYear,omitempty"`
+	ReleaseDate    pq.NullTime     `json:"ReleaseDate,omitempty"`
+	Metascore      sql.NullInt64   `json:"Metascore,omitempty"`
+	Plot           sql.NullString  `json:"Plot,omitempty"`
+	RuntimeMinutes sql.NullInt64   `json:"RuntimeMinutes,omitempty"`
+	Director       sql.NullString  `json:"Director,omitempty"`
+	Writers        pq.StringArray  `json:"Writers,omitempty"`
+	Actors         pq.StringArray  `json:"Actors,omitempty"`
+	Language       sql.NullString  `json:"Language,omitempty"`
+	Country        sql.NullString  `json:"Country,omitempty"`
+	PosterURL      sql.NullString  `json:"PosterURL,omitempty"`
+	ImdbID         sql.NullString  `json:"ImdbID,omitempty"`
+	Slug           sql.NullString  `json:"Slug,omitempty"`
+	DeletedAt      pq.NullTime     `json:"DeletedAt,omitempty"`
+	Provenance     types.JSONText  `json:"Provenance,omitempty"`
+	LockedFields   pq.StringArray  `json:"LockedFields,omitempty"`
+	Serial         sql.NullInt64   `json:"Serial,omitempty"`
 }
 
 func (sql *SQLMovies) toMovie() *models.Movie {
 
 	movie := models.Movie{
 		ID:             sql.ID.String,
-		Genres:         []string{sql.Genres.String},
+		Genres:         []string(sql.Genres),
 		LastModifiedAt: sql.LastModifiedAt,
 		CreatedAt:      sql.CreatedAt,
-		Rating:         sql.Rating.String,
-		ReleasedYear:   sql.ReleasedYear.String,
 		Title:          sql.Title.String,
+		Slug:           sql.Slug.String,
+		Plot:           sql.Plot.String,
+		Director:       sql.Director.String,
+		Language:       sql.Language.String,
+		Country:        sql.Country.String,
+		Poster:         sql.PosterURL.String,
+		ImdbID:         sql.ImdbID.String,
+	}
+	if sql.ReleaseYear.Valid {
+		movie.ReleasedYear = strconv.FormatInt(sql.ReleaseYear.Int64, 10)
+	}
+	if sql.RatingScore.Valid {
+		movie.Rating = strconv.FormatFloat(sql.RatingScore.Float64, 'f', 1, 64)
+	}
+	movie.CommunityRatingCount = sql.CommunityCount
+	if sql.CommunityScore.Valid {
+		movie.CommunityRating = &sql.CommunityScore.Float64
+	}
+	if sql.ReleaseDate.Valid {
+		releaseDate := strfmt.Date(sql.ReleaseDate.Time)
+		movie.ReleaseDate = &releaseDate
+	}
+	if sql.Metascore.Valid {
+		movie.Metascore = &sql.Metascore.Int64
+	}
+	if sql.RuntimeMinutes.Valid {
+		movie.Runtime = &sql.RuntimeMinutes.Int64
+	}
+	if len(sql.Writers) != 0 {
+		movie.Writers = []string(sql.Writers)
+	}
+	if len(sql.Actors) != 0 {
+		movie.Actors = []string(sql.Actors)
+	}
+	if len(sql.LockedFields) != 0 {
+		movie.LockedFields = []string(sql.LockedFields)
+	}
+	if sql.DeletedAt.Valid {
+		deletedAt := strfmt.DateTime(sql.DeletedAt.Time)
+		movie.DeletedAt = &deletedAt
+	}
+	if len(sql.Provenance) != 0 {
+		provenance := models.Provenance{}
+		if err := json.Unmarshal(sql.Provenance, &provenance); err == nil && len(provenance) != 0 {
+			movie.Provenance = provenance
+		}
 	}
 	return &movie
 }
@@ -0,0 +1,59 @@
+package movie
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/provider"
+)
+
+// manualProvenance returns the provenance of the fields which differ between the movies, recorded as
+// edited by hand. A cleared field counts as edited, the providers must not fill it again
+func manualProvenance(before, after *models.Movie) models.Provenance {
+	editedAt := strfmt.DateTime(time.Now().UTC())
+	provenance := models.Provenance{}
+	beforeValues := fieldValues(before)
+	for field, value := range fieldValues(after) {
+		if value != beforeValues[field] {
+			provenance[field] = models.FieldSource{
+				Provider:  provider.Manual,
+				FetchedAt: editedAt,
+			}
+		}
+	}
+	return provenance
+}
+
+// fieldValues returns the values of the provider fields of the movie, keyed by the Field constants
+func fieldValues(m *models.Movie) map[string]string {
+	values := map[string]string{
+		provider.FieldTitle:        m.Title,
+		provider.FieldReleasedYear: m.ReleasedYear,
+		provider.FieldGenres:       strings.Join(m.Genres, ","),
+		provider.FieldRating:       m.Rating,
+		provider.FieldReleaseDate:  "",
+		provider.FieldMetascore:    "",
+		provider.FieldPlot:         m.Plot,
+		provider.FieldRuntime:      "",
+		provider.FieldDirector:     m.Director,
+		provider.FieldWriters:      strings.Join(m.Writers, ","),
+		provider.FieldActors:       strings.Join(m.Actors, ","),
+		provider.FieldLanguage:     m.Language,
+		provider.FieldCountry:      m.Country,
+		provider.FieldPoster:       m.Poster,
+		provider.FieldImdbID:       m.ImdbID,
+	}
+	if m.ReleaseDate != nil {
+		values[provider.FieldReleaseDate] = m.ReleaseDate.String()
+	}
+	if m.Metascore != nil {
+		values[provider.FieldMetascore] = strconv.FormatInt(*m.Metascore, 10)
+	}
+	if m.Runtime != nil {
+		values[provider.FieldRuntime] = strconv.FormatInt(*m.Runtime, 10)
+	}
+	return values
+}
@@ -2,18 +2,25 @@ package movie
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
-	gomdb "github.com/eefret/go-imdb"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/google/uuid"
 	"github.com/ido50/sqlz"
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
 	"github.com/movieManagement/gen/models"
 	"github.com/movieManagement/gen/restapi/operations/movie"
-	ini "github.com/movieManagement/init"
+	"github.com/movieManagement/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -21,23 +28,57 @@ import (
 const (
 	// MovieTable . . .
 	MovieTable = "public.moviestbl as mv"
+
+	// pqUniqueViolation is the postgres error code of a unique constraint violation
+	pqUniqueViolation = "23505"
 )
 
 // sorted by field alias. Same as movieReturnFields
 var movieReturnFields = []string{
 	"COALESCE(mv.createddate, '2019-01-01') as CreatedAt",
 	"COALESCE(mv.title, '') as Title",
-	"COALESCE(mv.rating, '') as Rating",
-	"COALESCE(mv.releasedYear, '') as ReleasedYear",
-	"COALESCE(mv.genres, '') as Genres",
+	"mv.metascore as Metascore",
+	"mv.rating_score as RatingScore",
+	"mv.community_rating as CommunityScore",
+	"mv.community_rating_count as CommunityCount",
+	"mv.release_date as ReleaseDate",
+	"mv.release_year as ReleaseYear",
+	"mv.plot as Plot",
+	"mv.runtime_minutes as RuntimeMinutes",
+	"mv.director as Director",
+	"mv.writers as Writers",
+	"mv.actors as Actors",
+	"mv.language as Language",
+	"mv.country as Country",
+	"mv.poster_url as PosterURL",
+	"mv.imdb_id as ImdbID",
+	"COALESCE(mv.slug, '') as Slug",
+	movieGenresField,
 	"COALESCE(mv.lastmodifieddate, '2019-01-01') as LastModifiedAt",
 	"COALESCE(mv.sfid, '') as ID",
+	"mv.deleted_at as DeletedAt",
+	"mv.provenance as Provenance",
+	"mv.locked_fields as LockedFields",
+	`mv."Id" as Serial`,
 }
 
 // Repository interface includes a list of supported repository operations
 type Repository interface {
-	CreateMovie(ctx context.Context, params *movie.CreateMovieParams) (*models.Movie, error)
-	SearchMovies(ctx context.Context, params *movie.SearchMoviesParams) ([]*models.Movie, int64, error)
+	CreateMovie(ctx context.Context, params *movie.CreateMovieParams, provenance models.Provenance) (*models.Movie, error)
+	GetMovie(ctx context.Context, id string, includeDeleted bool) (*models.Movie, error)
+	GetMovieByTitle(ctx context.Context, title string, includeDeleted bool) (*models.Movie, error)
+	GetMovieByImdbID(ctx context.Context, imdbID string, includeDeleted bool) (*models.Movie, error)
+	UpdateMovie(ctx context.Context, id string, in *models.UpdateMovie, expected *time.Time) (*models.Movie, error)
+	PatchMovie(ctx context.Context, id string, patch models.MoviePatch, expected *time.Time) (*models.Movie, error)
+	RefreshMovie(ctx context.Context, id string, patch models.MoviePatch, provenance models.Provenance) (*models.Movie, error)
+	UnlockFields(ctx context.Context, id string, fields []string) (*models.Movie, error)
+	ListMovieIDs(ctx context.Context, filter string, after string) ([]string, error)
+	ListStaleMovieIDs(ctx context.Context, refreshedBefore time.Time, limit int) ([]string, error)
+	MarkRefreshed(ctx context.Context, id string) error
+	DeleteMovie(ctx context.Context, id string) error
+	RestoreMovie(ctx context.Context, id string) (*models.Movie, error)
+	PurgeMovies(ctx context.Context, deletedBefore time.Time) (int64, error)
+	SearchMovies(ctx context.Context, params *movie.SearchMoviesParams) ([]*models.Movie, int64, string, error)
 }
 
 type repository struct {
@@ -56,97 +97,787 @@ func (repo *repository) GetDB() *sqlx.DB {
 	return repo.db
 }
 
-// CreateMovie create the affiliation..
-func (repo *repository) CreateMovie(ctx context.Context, params *movie.CreateMovieParams) (*models.Movie, error) {
+// CreateMovie create the affiliation.. The provenance records the source of the fields looked up with the metadata providers,
+// the fields of a movie created with nil provenance are edited by hand and locked
+func (repo *repository) CreateMovie(ctx context.Context, params *movie.CreateMovieParams, provenance models.Provenance) (*models.Movie, error) {
 	logrus.Debugf("CreateMovie repo")
-	var movies []*models.Movie
 	sqlMovies := SQLMovies{}
 	uuid := uuid.New().String()
-	createMap := insertFields(params, repo)
+	createMap, err := insertFields(params, repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "CreateMovie")
+	}
+
+	year := ""
+	if createMap["release_year"] != nil {
+		year = fmt.Sprint(createMap["release_year"])
+	}
 
 	createMap["lastmodifieddate"] = sqlz.Indirect("now()::timestamp")
 	createMap["createddate"] = sqlz.Indirect("now()::timestamp")
 	createMap["sfid"] = uuid
+	createMap["slug"] = repo.uniqueSlug(params.Movie.Title, year, uuid)
+	if len(provenance) != 0 {
+		sources, errJSON := json.Marshal(provenance)
+		if errJSON != nil {
+			return nil, errors.Wrap(errJSON, "CreateMovie.MarshalProvenance")
+		}
+		createMap["provenance"] = string(sources)
+	}
+
+	err = sqlz.Newx(repo.db).Transactional(func(tx *sqlz.Tx) error {
+		var serial int64
+		err := tx.InsertInto(MovieTable).
+			ValueMap(createMap).
+			Returning(`mv."Id"`).
+			GetRow(&serial)
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "InsertQuery")
+		}
+
+		err = setMovieGenres(tx, serial, splitGenres(params.Movie.Genres))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Select(movieReturnFields...).
+			From(MovieTable).
+			Where(sqlz.Eq(`mv."Id"`, serial)).
+			GetRow(&sqlMovies)
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		if provenance == nil {
+			return recordEdit(tx, &sqlMovies, &models.Movie{})
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("error to create movie %v", err)
+		return nil, errors.Wrap(err, "CreateMovie")
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+func insertFields(params *movie.CreateMovieParams, repo *repository) (map[string]interface{}, error) {
+	insertMap, err := typedFields(params.Movie.ReleasedYear, params.Movie.ReleaseDate, params.Movie.Rating, params.Movie.Metascore)
+	if err != nil {
+		return nil, err
+	}
+
+	addIfNotEmpty(insertMap, "title", params.Movie.Title)
+
+	details, err := detailFields(movieDetails{
+		Plot:     params.Movie.Plot,
+		Runtime:  params.Movie.Runtime,
+		Director: params.Movie.Director,
+		Writers:  params.Movie.Writers,
+		Actors:   params.Movie.Actors,
+		Language: params.Movie.Language,
+		Country:  params.Movie.Country,
+		Poster:   params.Movie.Poster,
+		ImdbID:   params.Movie.ImdbID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for column, value := range details {
+		insertMap[column] = value
+	}
+
+	return insertMap, nil
+}
+
+// uniqueSlug builds the human readable slug for a new movie. A short sfid suffix is
+// appended when the slug is already taken or could be mistaken for a serial Id or sfid
+func (repo *repository) uniqueSlug(title, year, sfid string) string {
+	slug := util.Slugify(title + " " + year)
+	if slug == "" {
+		return sfid
+	}
+
+	_, errSerial := strconv.ParseInt(slug, 10, 64)
+	_, errUUID := uuid.Parse(slug)
+	if errSerial == nil || errUUID == nil {
+		return slug + "-" + sfid[0:8]
+	}
+
+	count, err := sqlz.Newx(repo.GetDB()).
+		Select("mv.slug").
+		From(MovieTable).
+		Where(sqlz.Eq("mv.slug", slug)).
+		GetCount()
+	if err != nil {
+		logrus.Errorf("error to check movie slug %v", err)
+	}
+	if count > 0 || err != nil {
+		return slug + "-" + sfid[0:8]
+	}
+	return slug
+}
+
+// GetMovie returns the movie matching the specified id, which may be the sfid UUID,
+// the numeric "Id" serial or the movie slug
+func (repo *repository) GetMovie(ctx context.Context, id string, includeDeleted bool) (*models.Movie, error) {
+	logrus.Debugf("GetMovie repo")
+	sqlMovies := SQLMovies{}
+	conditions := []sqlz.WhereCondition{IDCondition(id)}
+	if !includeDeleted {
+		conditions = append(conditions, notDeleted())
+	}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Select(movieReturnFields...).
+		From(MovieTable).
+		Where(conditions...).
+		GetRow(&sqlMovies)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "GetMovie")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "GetMovie.SelectQuery")
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+// GetMovieByTitle returns the first movie with the title, compared case insensitively
+func (repo *repository) GetMovieByTitle(ctx context.Context, title string, includeDeleted bool) (*models.Movie, error) {
+	logrus.Debugf("GetMovieByTitle repo")
+	sqlMovies := SQLMovies{}
+	conditions := []sqlz.WhereCondition{sqlz.SQLCond("lower(mv.title) = lower(?)", strings.TrimSpace(title))}
+	if !includeDeleted {
+		conditions = append(conditions, notDeleted())
+	}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Select(movieReturnFields...).
+		From(MovieTable).
+		Where(conditions...).
+		OrderBy(sqlz.Asc(`mv."Id"`)).
+		Limit(1).
+		GetRow(&sqlMovies)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "GetMovieByTitle")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "GetMovieByTitle.SelectQuery")
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+// GetMovieByImdbID returns the movie with the IMDb ID
+func (repo *repository) GetMovieByImdbID(ctx context.Context, imdbID string, includeDeleted bool) (*models.Movie, error) {
+	logrus.Debugf("GetMovieByImdbID repo")
+	sqlMovies := SQLMovies{}
+	conditions := []sqlz.WhereCondition{sqlz.Eq("mv.imdb_id", strings.TrimSpace(imdbID))}
+	if !includeDeleted {
+		conditions = append(conditions, notDeleted())
+	}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Select(movieReturnFields...).
+		From(MovieTable).
+		Where(conditions...).
+		GetRow(&sqlMovies)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "GetMovieByImdbID")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "GetMovieByImdbID.SelectQuery")
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+// IDCondition resolves the lookup column for the specified movie id, a SFID, a numeric ID or a
+// slug, of the moviestbl aliased mv
+func IDCondition(id string) sqlz.WhereCondition {
+	if _, err := uuid.Parse(id); err == nil {
+		return sqlz.Eq("mv.sfid", strings.ToLower(id))
+	}
+
+	if serial, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return sqlz.Eq(`mv."Id"`, serial)
+	}
+
+	return sqlz.Eq("mv.slug", strings.ToLower(id))
+}
+
+// notDeleted filters out the soft deleted movies
+func notDeleted() sqlz.WhereCondition {
+	return sqlz.IsNull("mv.deleted_at")
+}
+
+// UpdateMovie replaces all the editable fields of the movie
+func (repo *repository) UpdateMovie(ctx context.Context, id string, in *models.UpdateMovie, expected *time.Time) (*models.Movie, error) {
+	logrus.Debugf("UpdateMovie repo")
+	updateMap, err := typedFields(in.ReleasedYear, in.ReleaseDate, in.Rating, in.Metascore)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateMovie")
+	}
+	updateMap["title"] = nullIfEmpty(in.Title)
+	genres := splitGenres(in.Genres)
+
+	details, err := detailFields(movieDetails{
+		Plot:     in.Plot,
+		Runtime:  in.Runtime,
+		Director: in.Director,
+		Writers:  in.Writers,
+		Actors:   in.Actors,
+		Language: in.Language,
+		Country:  in.Country,
+		Poster:   in.Poster,
+		ImdbID:   in.ImdbID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateMovie")
+	}
+	for column, value := range details {
+		updateMap[column] = value
+	}
+
+	movie, err := repo.updateMovie(id, updateMap, &genres, expected, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "UpdateMovie")
+	}
+	return movie, nil
+}
+
+// PatchMovie applies the JSON merge patch to the movie
+func (repo *repository) PatchMovie(ctx context.Context, id string, patch models.MoviePatch, expected *time.Time) (*models.Movie, error) {
+	logrus.Debugf("PatchMovie repo")
+	updateMap, genres, err := patchFields(patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "PatchMovie")
+	}
+
+	movie, err := repo.updateMovie(id, updateMap, genres, expected, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "PatchMovie")
+	}
+	return movie, nil
+}
+
+// RefreshMovie applies the merge patch of the values looked up again with the metadata providers.
+// The provenance of the patched fields replaces their previous provenance
+func (repo *repository) RefreshMovie(ctx context.Context, id string, patch models.MoviePatch, provenance models.Provenance) (*models.Movie, error) {
+	logrus.Debugf("RefreshMovie repo")
+	updateMap, genres, err := patchFields(patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "RefreshMovie")
+	}
+	updateMap["refreshed_at"] = sqlz.Indirect("now()")
+
+	movie, err := repo.updateMovie(id, updateMap, genres, nil, provenance)
+	if err != nil {
+		return nil, errors.Wrap(err, "RefreshMovie")
+	}
+	return movie, nil
+}
+
+// UnlockFields unlocks the fields of the movie, all its locked fields when fields is empty
+func (repo *repository) UnlockFields(ctx context.Context, id string, fields []string) (*models.Movie, error) {
+	logrus.Debugf("UnlockFields repo")
+	sqlMovies := SQLMovies{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		err := tx.Select(movieReturnFields...).
+			From(MovieTable).
+			Where(IDCondition(id), notDeleted()).
+			Lock(sqlz.ForUpdate()).
+			GetRow(&sqlMovies)
+		if err == sql.ErrNoRows {
+			return errs.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		unlocked := make(map[string]bool)
+		for _, field := range fields {
+			unlocked[field] = true
+		}
+		locked := []string{}
+		for _, field := range sqlMovies.LockedFields {
+			if len(fields) != 0 && !unlocked[field] {
+				locked = append(locked, field)
+			}
+		}
+		if len(locked) == len(sqlMovies.LockedFields) {
+			return nil
+		}
+
+		err = setLockedFields(tx, &sqlMovies, locked)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Update(MovieTable).
+			Set("lastmodifieddate", sqlz.Indirect("date_trunc('milliseconds', now())::timestamp")).
+			Where(sqlz.Eq(`mv."Id"`, sqlMovies.Serial.Int64)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "UpdateQuery")
+		}
+
+		return tx.Select(movieReturnFields...).
+			From(MovieTable).
+			Where(sqlz.Eq(`mv."Id"`, sqlMovies.Serial.Int64)).
+			GetRow(&sqlMovies)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "UnlockFields")
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+// ListMovieIDs returns the serial IDs of the movies matching the $filter expression, of all
+// the movies when the filter is empty, in order. Only the IDs after the serial ID after are
+// returned when set. Soft deleted movies are left out
+func (repo *repository) ListMovieIDs(ctx context.Context, filter string, after string) ([]string, error) {
+	logrus.Debugf("ListMovieIDs repo")
+	conditions := []sqlz.WhereCondition{notDeleted()}
+	if after != "" {
+		serial, err := strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "ListMovieIDs.ParseAfter")
+		}
+		conditions = append(conditions, sqlz.Gt(`mv."Id"`, serial))
+	}
+	if strings.TrimSpace(filter) != "" {
+		filterCondition, err := parseFilter(filter)
+		if err != nil {
+			return nil, errors.Wrap(err, "ListMovieIDs.parseFilter")
+		}
+		conditions = append(conditions, filterCondition)
+	}
+
+	sqlMovies := []SQLMovies{}
+	err := sqlz.Newx(repo.GetDB()).
+		Select(`mv."Id" as Serial`).
+		From(MovieTable).
+		Where(conditions...).
+		OrderBy(sqlz.Asc(`mv."Id"`)).
+		GetAll(&sqlMovies)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "ListMovieIDs.SelectQuery")
+	}
+
+	return serialIDs(sqlMovies), nil
+}
 
-	err := sqlz.Newx(repo.db).
-		InsertInto(MovieTable).
-		ValueMap(createMap).
+// ListStaleMovieIDs returns the serial IDs of up to limit movies not looked up again with the metadata
+// providers since refreshedBefore, least recently refreshed first. A movie never refreshed is as old as
+// its creation
+func (repo *repository) ListStaleMovieIDs(ctx context.Context, refreshedBefore time.Time, limit int) ([]string, error) {
+	logrus.Debugf("ListStaleMovieIDs repo")
+	sqlMovies := []SQLMovies{}
+	err := sqlz.Newx(repo.GetDB()).
+		Select(`mv."Id" as Serial`).
+		From(MovieTable).
+		Where(notDeleted(), sqlz.SQLCond(refreshedAtField+" < ?", refreshedBefore.UTC())).
+		OrderBy(sqlz.Asc(refreshedAtField), sqlz.Asc(`mv."Id"`)).
+		Limit(int64(limit)).
+		GetAll(&sqlMovies)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "ListStaleMovieIDs.SelectQuery")
+	}
+	return serialIDs(sqlMovies), nil
+}
+
+// MarkRefreshed records a lookup of the movie which left it unchanged, e.g. when the providers do not
+// know it, so it is not picked as stale again before the others
+func (repo *repository) MarkRefreshed(ctx context.Context, id string) error {
+	logrus.Debugf("MarkRefreshed repo")
+	_, err := sqlz.Newx(repo.GetDB()).
+		Update(MovieTable).
+		Set("refreshed_at", sqlz.Indirect("now()")).
+		Where(IDCondition(id)).
+		Exec()
+	if err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "MarkRefreshed.UpdateQuery")
+	}
+	return nil
+}
+
+// refreshedAtField matches the index of the stale movie lookups
+const refreshedAtField = "COALESCE(mv.refreshed_at, mv.createddate, '2019-01-01')"
+
+func serialIDs(sqlMovies []SQLMovies) []string {
+	ids := make([]string, 0, len(sqlMovies))
+	for _, sqlMovie := range sqlMovies {
+		ids = append(ids, strconv.FormatInt(sqlMovie.Serial.Int64, 10))
+	}
+	return ids
+}
+
+// updateMovie locks the movie row, checks it is still at the expected version and applies the update.
+// The genres of the movie are replaced unless genres is nil. An update with nil provenance is edited
+// by hand, the fields it changes are locked. Otherwise it is a provider update, which leaves the locked
+// fields as they are, and its provenance is merged into the provenance of the movie
+func (repo *repository) updateMovie(id string, updateMap map[string]interface{}, genres *[]string, expected *time.Time, provenance models.Provenance) (*models.Movie, error) {
+	sqlMovies := SQLMovies{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		current := SQLMovies{}
+		err := tx.Select(movieReturnFields...).
+			From(MovieTable).
+			Where(IDCondition(id), notDeleted()).
+			Lock(sqlz.ForUpdate()).
+			GetRow(&current)
+		if err == sql.ErrNoRows {
+			return errs.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		if expected != nil && !sameVersion(current.LastModifiedAt, *expected) {
+			return errs.ErrConflict
+		}
+
+		if provenance != nil {
+			genres = dropLocked(current.LockedFields, updateMap, genres, provenance)
+		}
+
+		// the API exposes millisecond precision, so the stored version is truncated to match
+		updateMap["lastmodifieddate"] = sqlz.Indirect("date_trunc('milliseconds', now())::timestamp")
+
+		_, err = tx.Update(MovieTable).
+			SetMap(updateMap).
+			Where(sqlz.Eq(`mv."Id"`, current.Serial.Int64)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "UpdateQuery")
+		}
+
+		if genres != nil {
+			err = setMovieGenres(tx, current.Serial.Int64, *genres)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Select(movieReturnFields...).
+			From(MovieTable).
+			Where(sqlz.Eq(`mv."Id"`, current.Serial.Int64)).
+			GetRow(&sqlMovies)
+		if err != nil {
+			return errors.Wrap(err, "SelectQuery")
+		}
+
+		if provenance == nil {
+			return recordEdit(tx, &sqlMovies, current.toMovie())
+		}
+		return setProvenance(tx, &sqlMovies, provenance)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	return sqlMovies.toMovie(), nil
+}
+
+// setProvenance merges the provenance into the provenance of the movie
+func setProvenance(tx *sqlz.Tx, sqlMovies *SQLMovies, provenance models.Provenance) error {
+	if len(provenance) == 0 {
+		return nil
+	}
+
+	merged := models.Provenance{}
+	if len(sqlMovies.Provenance) != 0 {
+		if err := json.Unmarshal(sqlMovies.Provenance, &merged); err != nil {
+			return errors.Wrap(err, "setProvenance")
+		}
+	}
+	for field, source := range provenance {
+		merged[field] = source
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return errors.Wrap(err, "setProvenance")
+	}
+	_, err = tx.Update(MovieTable).
+		Set("provenance", string(body)).
+		Where(sqlz.Eq(`mv."Id"`, sqlMovies.Serial.Int64)).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "setProvenance.UpdateQuery")
+	}
+	sqlMovies.Provenance = body
+	return nil
+}
+
+// DeleteMovie soft deletes the movie by setting its deleted_at tombstone
+func (repo *repository) DeleteMovie(ctx context.Context, id string) error {
+	logrus.Debugf("DeleteMovie repo")
+	res, err := sqlz.Newx(repo.GetDB()).
+		Update(MovieTable).
+		Set("deleted_at", sqlz.Indirect("now()::timestamp")).
+		Set("lastmodifieddate", sqlz.Indirect("date_trunc('milliseconds', now())::timestamp")).
+		Where(IDCondition(id), notDeleted()).
+		Exec()
+	if err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "DeleteMovie.UpdateQuery")
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "DeleteMovie.RowsAffected")
+	}
+	if affected == 0 {
+		return errors.Wrap(errs.ErrNotFound, "DeleteMovie")
+	}
+	return nil
+}
+
+// RestoreMovie clears the deleted_at tombstone of the movie. Restoring a movie that
+// is not deleted simply returns it
+func (repo *repository) RestoreMovie(ctx context.Context, id string) (*models.Movie, error) {
+	logrus.Debugf("RestoreMovie repo")
+	sqlMovies := SQLMovies{}
+
+	err := sqlz.Newx(repo.GetDB()).
+		Update(MovieTable).
+		Set("deleted_at", nil).
+		Set("lastmodifieddate", sqlz.Indirect("CASE WHEN mv.deleted_at IS NULL THEN mv.lastmodifieddate ELSE date_trunc('milliseconds', now())::timestamp END")).
+		Where(IDCondition(id)).
 		Returning(movieReturnFields...).
 		GetRow(&sqlMovies)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(errs.ErrNotFound, "RestoreMovie")
+	}
 	if err != nil {
-		logrus.Errorf("error to create membership %v", err)
-		//return nil, errors.Wrap(err, "CreateMembership.Exec")
+		log.Error(err)
+		return nil, errors.Wrap(err, "RestoreMovie.UpdateQuery")
 	}
-	var movie = sqlMovies.toMovie()
 
-	movies = append(movies, movie)
+	return sqlMovies.toMovie(), nil
+}
 
-	return movies[0], nil
+// PurgeMovies permanently deletes the movies soft deleted before the specified date/time
+func (repo *repository) PurgeMovies(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	logrus.Debugf("PurgeMovies repo")
+	res, err := sqlz.Newx(repo.GetDB()).
+		DeleteFrom(MovieTable).
+		Where(sqlz.IsNotNull("mv.deleted_at"), sqlz.Lt("mv.deleted_at", deletedBefore)).
+		Exec()
+	if err != nil {
+		log.Error(err)
+		return 0, errors.Wrap(err, "PurgeMovies.DeleteQuery")
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "PurgeMovies.RowsAffected")
+	}
+	return purged, nil
 }
 
-func insertFields(params *movie.CreateMovieParams, repo *repository) map[string]interface{} {
-	var genresDetails *string
-	insertMap := make(map[string]interface{})
+// patchFields converts the merge patch to the update column map, a null value clears the column.
+// The patched genres are returned separately, nil when the patch leaves them untouched
+func patchFields(patch models.MoviePatch) (map[string]interface{}, *[]string, error) {
+	updateMap := make(map[string]interface{})
+	var genres *[]string
+
+	for key, value := range patch {
+		switch key {
+		case "LastModifiedAt":
+			// version check only, see expectedVersion
+		case "Genres":
+			genresList, err := patchList(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			patched := splitGenres(genresList)
+			genres = &patched
+		case "Writers", "Actors":
+			names, err := patchList(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			updateMap[strings.ToLower(key)] = detailList(names)
+		case "Metascore":
+			metascore, err := patchInteger(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			meta, err := parseMetascore(metascore)
+			if err != nil {
+				return nil, nil, err
+			}
+			updateMap["metascore"] = meta
+		case "Runtime":
+			minutes, err := patchInteger(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			runtime, err := parseRuntime(minutes)
+			if err != nil {
+				return nil, nil, err
+			}
+			updateMap["runtime_minutes"] = runtime
+		default:
+			text, ok := value.(string)
+			if value != nil && !ok {
+				return nil, nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("%s must be a string", key))
+			}
+			err := patchTextField(updateMap, key, text)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 
-	addIfNotEmpty(insertMap, "title", params.Movie.Title)
-	addIfNotEmpty(insertMap, "releasedYear", params.Movie.ReleasedYear)
-	addIfNotEmpty(insertMap, "rating", params.Movie.Rating)
+	return updateMap, genres, nil
+}
 
-	if len(params.Movie.Genres) > 0 {
-		val := strings.Join(params.Movie.Genres[:], ",")
-		genresDetails = &val
+// patchList returns a patched array of strings, nil when the patch clears it
+func patchList(key string, value interface{}) ([]string, error) {
+	values, ok := value.([]interface{})
+	if value != nil && !ok {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("%s must be an array of strings", key))
+	}
+	var list []string
+	for _, item := range values {
+		text, ok := item.(string)
+		if !ok {
+			return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("%s must be an array of strings", key))
+		}
+		list = append(list, text)
 	}
+	return list, nil
+}
 
-	addIfNotNil(insertMap, "genres", genresDetails)
+// patchInteger returns a patched integer, decoded from JSON as a float64, nil when the patch clears it
+func patchInteger(key string, value interface{}) (*int64, error) {
+	if value == nil {
+		return nil, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("%s must be an integer", key))
+	}
+	return swag.Int64(int64(number)), nil
+}
 
-	return insertMap
+// patchTextField validates a patched text value and sets its column, an empty value clears the column
+func patchTextField(updateMap map[string]interface{}, key, text string) error {
+	switch key {
+	case "Title":
+		updateMap["title"] = nullIfEmpty(text)
+	case "ReleasedYear":
+		year, date, err := parseReleasedYear(text)
+		if err != nil {
+			return err
+		}
+		updateMap["release_year"] = year
+		if date != nil {
+			updateMap["release_date"] = date
+		}
+	case "ReleaseDate":
+		var date *strfmt.Date
+		if text != "" {
+			parsed, err := time.Parse(releaseDateFormat, text)
+			if err != nil {
+				return errors.Wrap(errs.ErrInvalid, "ReleaseDate must be a date")
+			}
+			date = (*strfmt.Date)(&parsed)
+		}
+		value, err := parseReleaseDate(date)
+		if err != nil {
+			return err
+		}
+		updateMap["release_date"] = value
+	case "Rating":
+		score, err := parseRating(text)
+		if err != nil {
+			return err
+		}
+		updateMap["rating_score"] = score
+	case "Plot", "Director", "Language", "Country":
+		updateMap[strings.ToLower(key)] = detailText(text)
+	case "Poster":
+		poster, err := parsePoster(text)
+		if err != nil {
+			return err
+		}
+		updateMap["poster_url"] = poster
+	case "ImdbID":
+		imdbID, err := parseImdbID(text)
+		if err != nil {
+			return err
+		}
+		updateMap["imdb_id"] = imdbID
+	default:
+		return errors.Wrap(errs.ErrInvalid, fmt.Sprintf("field %s can not be patched", key))
+	}
+	return nil
 }
 
-// addIfNotEmpty simply adds the key/value pair if the key and value is not empty
-func addIfNotEmpty(m map[string]interface{}, key string, value string) {
-	if key != "" && value != "" {
-		m[key] = value
+// conflictOrErr maps unique constraint violations, such as an IMDb ID already taken, to errs.ErrConflict
+func conflictOrErr(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation {
+		return errs.ErrConflict
 	}
+	return err
 }
 
-/* // addIfNotNil simply adds the key/value pair if the key and value is not nil
-func addIfNotNilBool(m map[string]interface{}, key string, value *bool) {
-	if key != "" && value != nil {
-		m[key] = *value
+// nullIfEmpty returns nil for an empty value so the column is stored as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
 	}
-} */
+	return value
+}
 
-// addIfNotNil simply adds the key/value pair if the key and value is not nil
-func addIfNotNil(m map[string]interface{}, key string, value *string) {
-	if key != "" && value != nil {
-		m[key] = *value
+// addIfNotEmpty simply adds the key/value pair if the key and value is not empty
+func addIfNotEmpty(m map[string]interface{}, key string, value string) {
+	if key != "" && value != "" {
+		m[key] = value
 	}
 }
 
 // SearchMovies returns a list of movies based on the input
-// parameters and security permissions
-func (repo *repository) SearchMovies(ctx context.Context, params *movie.SearchMoviesParams) ([]*models.Movie, int64, error) {
+// parameters and security permissions, along with the total count
+// and the cursor of the next page
+func (repo *repository) SearchMovies(ctx context.Context, params *movie.SearchMoviesParams) ([]*models.Movie, int64, string, error) {
 	log.Debugf("entered function ListCommunities")
 	code := "SearchMovies"
-	community, count, err := getMovies(ctx, params, repo, code)
+	community, count, nextCursor, err := getMovies(ctx, params, repo, code)
 	if err != nil {
 		log.Error(err)
-		return nil, 0, errors.Wrap(err, "ListCommunities.getCommunities")
+		return nil, 0, "", errors.Wrap(err, "ListCommunities.getCommunities")
 	}
-	return community, count, nil
+	return community, count, nextCursor, nil
 }
 
-func getMovies(ctx context.Context, params *movie.SearchMoviesParams, repo *repository, code string) ([]*models.Movie, int64, error) {
+func getMovies(ctx context.Context, params *movie.SearchMoviesParams, repo *repository, code string) ([]*models.Movie, int64, string, error) {
 	pageSize, err := strconv.Atoi(*params.PageSize)
 	if err != nil {
 		log.Error(err)
-		return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "convertPageSize"))
+		return nil, 0, "", errors.Wrap(err, fmt.Sprintf("%s.%s", code, "convertPageSize"))
 	}
 
 	offset, err := strconv.Atoi(*params.Offset)
 	if err != nil {
 		log.Error(err)
-		return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "convertOffset"))
+		return nil, 0, "", errors.Wrap(err, fmt.Sprintf("%s.%s", code, "convertOffset"))
 	}
 
 	var id, title, rating, year string
@@ -175,7 +906,6 @@ func getMovies(ctx context.Context, params *movie.SearchMoviesParams, repo *repo
 		year = *params.Year
 	}
 
-	var createdMovie *models.Movie
 	var movieArray []*models.Movie
 	sqlMovies := []SQLMovies{}
 	conditions := []sqlz.WhereCondition{}
@@ -189,71 +919,94 @@ func getMovies(ctx context.Context, params *movie.SearchMoviesParams, repo *repo
 	}
 
 	if rating != "%" {
-		conditions = append(conditions, sqlz.Eq("mv.rating", rating))
+		score, errRating := parseRating(rating)
+		if errRating != nil {
+			return nil, 0, "", errors.Wrap(errRating, fmt.Sprintf("%s.%s", code, "parseRating"))
+		}
+		conditions = append(conditions, sqlz.Eq("mv.rating_score", score))
 	}
 
 	if year != "%" {
-		conditions = append(conditions, sqlz.Eq("mv.releasedYear", year))
+		releaseYear, _, errYear := parseReleasedYear(year)
+		if errYear != nil {
+			return nil, 0, "", errors.Wrap(errYear, fmt.Sprintf("%s.%s", code, "parseReleasedYear"))
+		}
+		conditions = append(conditions, sqlz.Eq("mv.release_year", releaseYear))
 	}
 
-	if len(params.Genres) != 0 {
-		var genresList []interface{}
-		for _, val := range params.Genres {
-			genresList = append(genresList, val)
+	if params.DollarFilter != nil && strings.TrimSpace(*params.DollarFilter) != "" {
+		filterCondition, errFilter := parseFilter(*params.DollarFilter)
+		if errFilter != nil {
+			return nil, 0, "", errors.Wrap(errFilter, fmt.Sprintf("%s.%s", code, "parseFilter"))
 		}
-		conditions = append(conditions, sqlz.In("mv.genres", genresList...))
+		conditions = append(conditions, filterCondition)
 	}
 
-	query := sqlz.Newx(repo.GetDB()).
+	if params.IncludeDeleted == nil || !*params.IncludeDeleted {
+		conditions = append(conditions, notDeleted())
+	}
+
+	if genres := splitGenres(params.Genres); len(genres) != 0 {
+		match := ""
+		if params.GenresMatch != nil {
+			match = *params.GenresMatch
+		}
+		conditions = append(conditions, genresCondition(genres, match))
+	}
+
+	sortKeys, err := parseSort(params.OrderBy, params.SortDir)
+	if err != nil {
+		return nil, 0, "", errors.Wrap(err, fmt.Sprintf("%s.%s", code, "parseSort"))
+	}
+
+	count, errCount := sqlz.Newx(repo.GetDB()).
 		Select(movieReturnFields...).
 		From(MovieTable).
 		Where(conditions...).
+		GetCount()
+	if errCount != nil {
+		log.Error(errCount)
+		return nil, 0, "", errors.Wrap(errCount, fmt.Sprintf("%s.%s", code, "GetCount"))
+	}
+
+	// a cursor continues after the last movie of the previous page, and replaces the offset
+	pageConditions := conditions
+	if params.Cursor != nil && *params.Cursor != "" {
+		values, errCursor := decodeCursor(sortKeys, *params.Cursor)
+		if errCursor != nil {
+			return nil, 0, "", errors.Wrap(errCursor, fmt.Sprintf("%s.%s", code, "decodeCursor"))
+		}
+		pageConditions = append(pageConditions, afterCondition(sortKeys, values))
+		offset = 0
+	}
+
+	query := sqlz.Newx(repo.GetDB()).
+		Select(movieReturnFields...).
+		From(MovieTable).
+		Where(pageConditions...).
+		OrderBy(orderColumns(sortKeys)...).
 		Limit(int64(pageSize)).
 		Offset(int64(offset))
 
 	sql, b := query.ToSQL(true)
 	log.Info(sql, b)
 
-	count, errCount := query.GetCount()
-	if errCount != nil {
-		log.Error(err)
-		return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "GetCount"))
-	}
 	err = query.GetAll(&sqlMovies)
 
 	if err != nil {
 		log.Error(err)
-		return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "SelectQuery"))
+		return nil, 0, "", errors.Wrap(err, fmt.Sprintf("%s.%s", code, "SelectQuery"))
 	}
 	for _, sqlMovie := range sqlMovies {
 		var movieData = sqlMovie.toMovie()
 		movieArray = append(movieArray, movieData)
 	}
 
-	if len(movieArray) == 0 {
-		imdb := ini.GetImdbInit()
-		movieObject, err := imdb.MovieByTitle(&gomdb.QueryData{Title: *params.Title})
-		if err != nil {
-			log.Error(err)
-			return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "MovieByTitle"))
-		}
-		log.Debugf("movieObject %s", movieObject)
-		if movieObject != nil {
-			var in movie.CreateMovieParams
-			in.Movie.Title = movieObject.Title
-			in.Movie.Rating = movieObject.ImdbRating
-			in.Movie.ReleasedYear = movieObject.Released
-			in.Movie.Genres = []string{movieObject.Genre}
-			createdMovie, err = repo.CreateMovie(ctx, &in)
-			if err != nil {
-				log.Error(err)
-				return nil, 0, errors.Wrap(err, fmt.Sprintf("%s.%s", code, "CreateMovie"))
-			}
-			if createdMovie != nil {
-				count = count + 1
-			}
-		}
+	// a full page may be followed by more movies
+	nextCursor := ""
+	if len(sqlMovies) == pageSize {
+		nextCursor = encodeCursor(sortKeys, &sqlMovies[len(sqlMovies)-1])
 	}
-	movieArray = append(movieArray, createdMovie)
-	return movieArray, count, nil
+
+	return movieArray, count, nextCursor, nil
 }
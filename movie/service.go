@@ -3,42 +3,238 @@ package movie
 import (
 	"context"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/errs"
 	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/admin"
 	"github.com/movieManagement/gen/restapi/operations/movie"
+	"github.com/movieManagement/jobs"
+	"github.com/movieManagement/provider"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
-// Service interface is a list of services for the affiliation
+// Service interface is a list of services for the affiliation. The changes are checked against
+// the auth policy, the Run methods and EnrichMovie run the jobs enqueued by the allowed callers
 type Service interface {
 	CreateMovie(ctx context.Context, in *movie.CreateMovieParams) (*models.Movie, error)
+	GetMovie(ctx context.Context, in *movie.GetmovieParams) (*models.Movie, error)
+	UpdateMovie(ctx context.Context, in *movie.UpdateMovieParams) (*models.Movie, error)
+	PatchMovie(ctx context.Context, in *movie.PatchMovieParams) (*models.Movie, error)
+	DeleteMovie(ctx context.Context, in *movie.DeleteMovieParams) error
+	RestoreMovie(ctx context.Context, in *movie.RestoreMovieParams) (*models.Movie, error)
+	UnlockMovie(ctx context.Context, in *movie.UnlockMovieParams) (*models.Movie, error)
+	UnlockMovieField(ctx context.Context, in *movie.UnlockMovieFieldParams) (*models.Movie, error)
+	PurgeMovies(ctx context.Context, in *admin.PurgeMoviesParams) (*models.PurgeResult, error)
 	SearchMovies(ctx context.Context, in *movie.SearchMoviesParams) (*models.MovieList, error)
+	EnrichMovie(ctx context.Context, title string) (*models.Movie, error)
+	ImportMovies(ctx context.Context, in *movie.ImportMoviesParams) (*models.Job, error)
+	BulkEditMovies(ctx context.Context, in *movie.BulkEditMoviesParams) (*models.Job, error)
+	ReenrichMovies(ctx context.Context, in *admin.ReenrichMoviesParams) (*models.Job, error)
+	RunImport(ctx context.Context, titles []string) error
+	RunBulkEdit(ctx context.Context, filter string, patch models.MoviePatch) error
+	RunReenrich(ctx context.Context, filter string) error
+	RunRefreshStale(ctx context.Context, maxAge time.Duration, budget int) error
 }
 
 type service struct {
-	repo Repository
+	repo           Repository
+	purgeRetention time.Duration
+	metadata       provider.MetadataProvider
+	enqueuer       Enqueuer
 }
 
-// New is a simple helper function to create a service instance
-func New(repo Repository) Service {
+// New is a simple helper function to create a service instance. Soft deleted movies
+// are purged once they have been deleted for longer than purgeRetention. Searches by
+// title missing from the database are looked up with the metadata provider, if any,
+// by the jobs of the enqueuer, which also runs the imports, bulk edits and re-enrichments
+func New(repo Repository, purgeRetention time.Duration, metadata provider.MetadataProvider, enqueuer Enqueuer) Service {
 	return &service{
-		repo: repo,
+		repo:           repo,
+		purgeRetention: purgeRetention,
+		metadata:       metadata,
+		enqueuer:       enqueuer,
 	}
 }
 
 // CreateMovie service definition
 func (s *service) CreateMovie(ctx context.Context, in *movie.CreateMovieParams) (*models.Movie, error) {
 	logrus.Debugf("entered service CreateAffiliation")
-	movie, err := s.repo.CreateMovie(ctx, in)
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.CreateMovie")
+	}
+	movie, err := s.repo.CreateMovie(ctx, in, nil)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.CreateMovie")
+	}
+	return movie, nil
+}
+
+// GetMovie service definition
+func (s *service) GetMovie(ctx context.Context, in *movie.GetmovieParams) (*models.Movie, error) {
+	log.Debugf("entered service GetMovie")
+	includeDeleted, err := authorizeDeleted(ctx, in.IncludeDeleted)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.GetMovie")
+	}
+	movie, err := s.repo.GetMovie(ctx, in.ID, includeDeleted)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.GetMovie")
+	}
+	return movie, nil
+}
+
+// authorizeDeleted returns whether the soft deleted movies are read. Reading them is an
+// administration of the movies, anonymous callers are rejected with 401 and the others with 403
+func authorizeDeleted(ctx context.Context, includeDeleted *bool) (bool, error) {
+	if !swag.BoolValue(includeDeleted) {
+		return false, nil
+	}
+	if err := auth.Authorize(ctx, auth.AdministerMovies); err != nil {
+		return false, errors.Wrap(err, "includeDeleted")
+	}
+	return true, nil
+}
+
+// UpdateMovie service definition
+func (s *service) UpdateMovie(ctx context.Context, in *movie.UpdateMovieParams) (*models.Movie, error) {
+	log.Debugf("entered service UpdateMovie")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.UpdateMovie")
+	}
+	expected, err := expectedVersion(in.IfMatch, in.Movie.LastModifiedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.UpdateMovie")
+	}
+
+	movie, err := s.repo.UpdateMovie(ctx, in.ID, in.Movie, expected)
 	if err != nil {
 		log.Error(err)
-		return nil, errors.Wrap(err, "service.CreateAffiliation")
+		return nil, errors.Wrap(err, "service.UpdateMovie")
 	}
 	return movie, nil
 }
 
+// PatchMovie service definition
+func (s *service) PatchMovie(ctx context.Context, in *movie.PatchMovieParams) (*models.Movie, error) {
+	log.Debugf("entered service PatchMovie")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.PatchMovie")
+	}
+	var lastModifiedAt strfmt.DateTime
+	if value, ok := in.Patch["LastModifiedAt"]; ok && value != nil {
+		text, _ := value.(string)
+		parsed, err := strfmt.ParseDateTime(text)
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrInvalid, "service.PatchMovie: LastModifiedAt must be a date-time")
+		}
+		lastModifiedAt = parsed
+	}
+
+	expected, err := expectedVersion(in.IfMatch, lastModifiedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.PatchMovie")
+	}
+
+	movie, err := s.repo.PatchMovie(ctx, in.ID, in.Patch, expected)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.PatchMovie")
+	}
+	return movie, nil
+}
+
+// DeleteMovie service definition
+func (s *service) DeleteMovie(ctx context.Context, in *movie.DeleteMovieParams) error {
+	log.Debugf("entered service DeleteMovie")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return errors.Wrap(err, "service.DeleteMovie")
+	}
+	err := s.repo.DeleteMovie(ctx, in.ID)
+	if err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "service.DeleteMovie")
+	}
+	return nil
+}
+
+// RestoreMovie service definition
+func (s *service) RestoreMovie(ctx context.Context, in *movie.RestoreMovieParams) (*models.Movie, error) {
+	log.Debugf("entered service RestoreMovie")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.RestoreMovie")
+	}
+	movie, err := s.repo.RestoreMovie(ctx, in.ID)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.RestoreMovie")
+	}
+	return movie, nil
+}
+
+// UnlockMovie service definition
+func (s *service) UnlockMovie(ctx context.Context, in *movie.UnlockMovieParams) (*models.Movie, error) {
+	log.Debugf("entered service UnlockMovie")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.UnlockMovie")
+	}
+
+	movie, err := s.repo.UnlockFields(ctx, in.ID, nil)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.UnlockMovie")
+	}
+	return movie, nil
+}
+
+// UnlockMovieField service definition
+func (s *service) UnlockMovieField(ctx context.Context, in *movie.UnlockMovieFieldParams) (*models.Movie, error) {
+	log.Debugf("entered service UnlockMovieField")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.UnlockMovieField")
+	}
+
+	movie, err := s.repo.UnlockFields(ctx, in.ID, []string{in.Field})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.UnlockMovieField")
+	}
+	return movie, nil
+}
+
+// PurgeMovies service definition
+func (s *service) PurgeMovies(ctx context.Context, in *admin.PurgeMoviesParams) (*models.PurgeResult, error) {
+	log.Debugf("entered service PurgeMovies")
+	if err := auth.Authorize(ctx, auth.AdministerMovies); err != nil {
+		return nil, errors.Wrap(err, "service.PurgeMovies")
+	}
+	retention := s.purgeRetention
+	if in.RetentionDays != nil {
+		retention = time.Duration(*in.RetentionDays) * 24 * time.Hour
+	}
+
+	deletedBefore := time.Now().Add(-retention)
+	purged, err := s.repo.PurgeMovies(ctx, deletedBefore)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.PurgeMovies")
+	}
+
+	logrus.Infof("purged %d movies deleted before %s", purged, deletedBefore)
+	return &models.PurgeResult{
+		Purged:        purged,
+		DeletedBefore: strfmt.DateTime(deletedBefore),
+	}, nil
+}
+
 // SearchMovies service definition
 func (s *service) SearchMovies(ctx context.Context, in *movie.SearchMoviesParams) (*models.MovieList, error) {
 	log.Debugf("entered service ListCommunities")
@@ -48,13 +244,29 @@ func (s *service) SearchMovies(ctx context.Context, in *movie.SearchMoviesParams
 	var ol models.MovieList
 	var err error
 	var count int64
+	var nextCursor string
+
+	if _, err = authorizeDeleted(ctx, in.IncludeDeleted); err != nil {
+		return nil, errors.Wrap(err, "service.SearchMovies")
+	}
 
-	movies, count, err = s.repo.SearchMovies(ctx, in)
+	movies, count, nextCursor, err = s.repo.SearchMovies(ctx, in)
 	if err != nil {
 		log.Error(err)
 		return nil, errors.Wrap(err, "service.SearchMovies")
 	}
 
+	// a provider failure leaves the search results as they are, and the movie found is only
+	// listed when it matches the search
+	if len(movies) == 0 && titleOnly(in) {
+		created, enrichment := s.searchMiss(ctx, in)
+		if created != nil && sameTitle(created.Title, *in.Title) {
+			movies = append(movies, created)
+			count++
+		}
+		meta.Enrichment = enrichment
+	}
+
 	offset, err := strconv.Atoi(*in.Offset)
 	if err != nil {
 		log.Error(err)
@@ -73,10 +285,173 @@ func (s *service) SearchMovies(ctx context.Context, in *movie.SearchMoviesParams
 		movies = make([]*models.Movie, 0)
 	}
 
+	// the offset is ignored when paging with a cursor
+	if in.Cursor != nil && *in.Cursor != "" {
+		offset = 0
+	}
+
 	meta.Offset = int64(offset)
 	meta.PageSize = int64(pageSize)
 	meta.TotalSize = count
+	meta.NextCursor = nextCursor
 	ol.Data = movies
 	ol.Metadata = &meta
 	return &ol, nil
 }
+
+// ImportMovies service definition
+func (s *service) ImportMovies(ctx context.Context, in *movie.ImportMoviesParams) (*models.Job, error) {
+	log.Debugf("entered service ImportMovies")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.ImportMovies")
+	}
+	titles := make([]string, 0, len(in.Import.Titles))
+	for _, title := range in.Import.Titles {
+		if title = strings.TrimSpace(title); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	if len(titles) == 0 {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ImportMovies: Titles must not be empty")
+	}
+
+	job, err := s.enqueuer.Enqueue(ctx, ImportJob, importPayload{Titles: titles})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ImportMovies")
+	}
+	return job.ToJob(), nil
+}
+
+// BulkEditMovies service definition
+func (s *service) BulkEditMovies(ctx context.Context, in *movie.BulkEditMoviesParams) (*models.Job, error) {
+	log.Debugf("entered service BulkEditMovies")
+	if err := auth.Authorize(ctx, auth.EditMovies); err != nil {
+		return nil, errors.Wrap(err, "service.BulkEditMovies")
+	}
+	// the filter and the patch are checked up front, the job would fail on every movie otherwise
+	filter := strings.TrimSpace(swag.StringValue(in.Edit.Filter))
+	if filter == "" {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.BulkEditMovies: Filter must not be empty")
+	}
+	if _, err := parseFilter(filter); err != nil {
+		return nil, errors.Wrap(err, "service.BulkEditMovies")
+	}
+	updateMap, genres, err := patchFields(in.Edit.Patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.BulkEditMovies")
+	}
+	if len(updateMap) == 0 && genres == nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.BulkEditMovies: Patch must not be empty")
+	}
+
+	job, err := s.enqueuer.Enqueue(ctx, BulkEditJob, bulkEditPayload{Filter: filter, Patch: in.Edit.Patch})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.BulkEditMovies")
+	}
+	return job.ToJob(), nil
+}
+
+// ReenrichMovies service definition
+func (s *service) ReenrichMovies(ctx context.Context, in *admin.ReenrichMoviesParams) (*models.Job, error) {
+	log.Debugf("entered service ReenrichMovies")
+	if err := auth.Authorize(ctx, auth.AdministerMovies); err != nil {
+		return nil, errors.Wrap(err, "service.ReenrichMovies")
+	}
+	if s.metadata == nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ReenrichMovies: "+errNoMetadataProvider.Error())
+	}
+
+	var filter string
+	if in.Reenrich != nil {
+		filter = strings.TrimSpace(in.Reenrich.Filter)
+	}
+	if filter != "" {
+		if _, err := parseFilter(filter); err != nil {
+			return nil, errors.Wrap(err, "service.ReenrichMovies")
+		}
+	}
+
+	job, err := s.enqueuer.Enqueue(ctx, ReenrichJob, reenrichPayload{Filter: filter})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ReenrichMovies")
+	}
+	return job.ToJob(), nil
+}
+
+// RunImport imports the movies by title, the titles the providers do not know are reported as errors.
+// A job claimed again skips the titles processed by its previous attempts
+func (s *service) RunImport(ctx context.Context, titles []string) error {
+	logrus.Debugf("entered service RunImport")
+	done, failed, _ := jobs.Resume(ctx)
+	if processed := int(done + failed); processed < len(titles) {
+		titles = titles[processed:]
+	} else {
+		titles = nil
+	}
+	return forEach(ctx, titles, func(title string) error {
+		_, err := s.enrich(ctx, title)
+		return err
+	})
+}
+
+// RunBulkEdit applies the patch to the movies matching the filter. A job claimed again goes on
+// after the last movie patched by its previous attempts
+func (s *service) RunBulkEdit(ctx context.Context, filter string, patch models.MoviePatch) error {
+	logrus.Debugf("entered service RunBulkEdit")
+	_, _, after := jobs.Resume(ctx)
+	ids, err := s.repo.ListMovieIDs(ctx, filter, after)
+	if err != nil {
+		return errors.Wrap(err, "service.RunBulkEdit")
+	}
+
+	return forEach(ctx, ids, func(id string) error {
+		_, err := s.repo.PatchMovie(ctx, id, patch, nil)
+		return err
+	})
+}
+
+// RunReenrich looks up the movies matching the filter again with the metadata provider. A job
+// claimed again goes on after the last movie looked up by its previous attempts
+func (s *service) RunReenrich(ctx context.Context, filter string) error {
+	logrus.Debugf("entered service RunReenrich")
+	_, _, after := jobs.Resume(ctx)
+	ids, err := s.repo.ListMovieIDs(ctx, filter, after)
+	if err != nil {
+		return errors.Wrap(err, "service.RunReenrich")
+	}
+
+	return forEach(ctx, ids, func(id string) error {
+		_, err := s.refresh(ctx, id)
+		return err
+	})
+}
+
+// RunRefreshStale looks up again up to budget movies not refreshed for longer than maxAge, least
+// recently refreshed first. The fields edited by hand are kept
+func (s *service) RunRefreshStale(ctx context.Context, maxAge time.Duration, budget int) error {
+	logrus.Debugf("entered service RunRefreshStale")
+	if s.metadata == nil {
+		return errNoMetadataProvider
+	}
+
+	// the movies looked up by the previous attempts of the job count against the budget
+	done, failed, _ := jobs.Resume(ctx)
+	budget -= int(done + failed)
+	if budget <= 0 {
+		return nil
+	}
+
+	ids, err := s.repo.ListStaleMovieIDs(ctx, time.Now().Add(-maxAge), budget)
+	if err != nil {
+		return errors.Wrap(err, "service.RunRefreshStale")
+	}
+
+	logrus.Infof("refreshing %d movies not refreshed for %s", len(ids), maxAge)
+	return forEach(ctx, ids, func(id string) error {
+		_, err := s.refresh(ctx, id)
+		return err
+	})
+}
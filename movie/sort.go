@@ -0,0 +1,203 @@
+package movie
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ido50/sqlz"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+const (
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+// sortField is a field the movies can be ordered by. The expression matches movieReturnFields
+// so NULLs sort as the returned values, and value reads the sort key back from a returned row
+type sortField struct {
+	expr  string
+	value func(m *SQLMovies) string
+}
+
+// sortFields is the whitelist of the orderBy fields, keyed by lower case field name
+var sortFields = map[string]sortField{
+	"title": {
+		expr:  "COALESCE(mv.title, '')",
+		value: func(m *SQLMovies) string { return m.Title.String },
+	},
+	"year": {
+		expr:  "COALESCE(mv.release_year, 0)",
+		value: func(m *SQLMovies) string { return strconv.FormatInt(m.ReleaseYear.Int64, 10) },
+	},
+	"releasedate": {
+		expr:  "COALESCE(mv.release_date, '0001-01-01')",
+		value: func(m *SQLMovies) string { return nullDate(m.ReleaseDate).Format(releaseDateFormat) },
+	},
+	"rating": {
+		expr:  "COALESCE(mv.rating_score, -1)",
+		value: func(m *SQLMovies) string { return nullFloat(m.RatingScore, -1) },
+	},
+	"metascore": {
+		expr:  "COALESCE(mv.metascore, -1)",
+		value: func(m *SQLMovies) string { return nullInt(m.Metascore, -1) },
+	},
+	"runtime": {
+		expr:  "COALESCE(mv.runtime_minutes, -1)",
+		value: func(m *SQLMovies) string { return nullInt(m.RuntimeMinutes, -1) },
+	},
+	"createdat": {
+		expr:  "COALESCE(mv.createddate, '2019-01-01')",
+		value: func(m *SQLMovies) string { return time.Time(m.CreatedAt).Format(time.RFC3339Nano) },
+	},
+	"lastmodifiedat": {
+		expr:  "COALESCE(mv.lastmodifieddate, '2019-01-01')",
+		value: func(m *SQLMovies) string { return time.Time(m.LastModifiedAt).Format(time.RFC3339Nano) },
+	},
+}
+
+// nullFloat formats the value, or the fallback of the sort expression when NULL
+func nullFloat(value sql.NullFloat64, fallback float64) string {
+	if !value.Valid {
+		return strconv.FormatFloat(fallback, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value.Float64, 'f', -1, 64)
+}
+
+// nullInt formats the value, or the fallback of the sort expression when NULL
+func nullInt(value sql.NullInt64, fallback int64) string {
+	if !value.Valid {
+		return strconv.FormatInt(fallback, 10)
+	}
+	return strconv.FormatInt(value.Int64, 10)
+}
+
+// nullDate returns the date, or the fallback of the sort expression when NULL
+func nullDate(value pq.NullTime) time.Time {
+	if !value.Valid {
+		return time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return value.Time
+}
+
+// sortTiebreaker is the unique key appended to every sort so the order is stable across pages
+var sortTiebreaker = sortKey{
+	name: "id",
+	sortField: sortField{
+		expr:  `mv."Id"`,
+		value: func(m *SQLMovies) string { return strconv.FormatInt(m.Serial.Int64, 10) },
+	},
+}
+
+// sortKey is one key of the movie sort order
+type sortKey struct {
+	sortField
+	name string
+	desc bool
+}
+
+// parseSort converts the orderBy and sortDir parameters to the sort keys. orderBy is a comma
+// separated list of fields, each optionally suffixed with :asc or :desc, the fields without a
+// suffix use sortDir. The unique tiebreaker key is always appended last, ascending unless it
+// is the only key
+func parseSort(orderBy, sortDir *string) ([]sortKey, error) {
+	defaultDesc := false
+	if sortDir != nil {
+		switch strings.ToLower(*sortDir) {
+		case sortAsc, "":
+		case sortDesc:
+			defaultDesc = true
+		default:
+			return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("unknown sortDir %s", *sortDir))
+		}
+	}
+
+	keys := []sortKey{}
+	if orderBy != nil && strings.TrimSpace(*orderBy) != "" {
+		seen := make(map[string]bool)
+		for _, item := range strings.Split(*orderBy, ",") {
+			name, dir := strings.TrimSpace(item), ""
+			if i := strings.Index(name, ":"); i >= 0 {
+				name, dir = name[:i], strings.ToLower(name[i+1:])
+			}
+
+			field, ok := sortFields[strings.ToLower(name)]
+			if !ok {
+				return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("unknown orderBy field %s", name))
+			}
+			if seen[strings.ToLower(name)] {
+				return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("duplicate orderBy field %s", name))
+			}
+			seen[strings.ToLower(name)] = true
+
+			key := sortKey{sortField: field, name: strings.ToLower(name), desc: defaultDesc}
+			switch dir {
+			case "":
+			case sortAsc:
+				key.desc = false
+			case sortDesc:
+				key.desc = true
+			default:
+				return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("unknown sort direction %s for %s", dir, name))
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	tiebreaker := sortTiebreaker
+	if len(keys) == 0 {
+		// without orderBy the movies are sorted by the tiebreaker alone, in sortDir order
+		tiebreaker.desc = defaultDesc
+	}
+	return append(keys, tiebreaker), nil
+}
+
+// orderColumns returns the ORDER BY columns of the sort keys
+func orderColumns(keys []sortKey) []sqlz.SQLStmt {
+	columns := make([]sqlz.SQLStmt, 0, len(keys))
+	for _, key := range keys {
+		if key.desc {
+			columns = append(columns, sqlz.Desc(key.expr))
+		} else {
+			columns = append(columns, sqlz.Asc(key.expr))
+		}
+	}
+	return columns
+}
+
+// sortSignature identifies the sort order a cursor was issued for
+func sortSignature(keys []sortKey) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		dir := sortAsc
+		if key.desc {
+			dir = sortDesc
+		}
+		parts = append(parts, key.name+":"+dir)
+	}
+	return strings.Join(parts, ",")
+}
+
+// afterCondition returns the keyset condition selecting the rows that sort after the specified
+// sort key values: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... with < for descending keys
+func afterCondition(keys []sortKey, values []string) sqlz.WhereCondition {
+	alternatives := make([]sqlz.WhereCondition, 0, len(keys))
+	for i, key := range keys {
+		conds := make([]sqlz.WhereCondition, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, sqlz.Eq(keys[j].expr, values[j]))
+		}
+		if key.desc {
+			conds = append(conds, sqlz.Lt(key.expr, values[i]))
+		} else {
+			conds = append(conds, sqlz.Gt(key.expr, values[i]))
+		}
+		alternatives = append(alternatives, sqlz.And(conds...))
+	}
+	return sqlz.Or(alternatives...)
+}
@@ -0,0 +1,245 @@
+package movie
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// minReleaseYear is the year of the oldest surviving motion picture
+	minReleaseYear = 1888
+	// maxReleaseYearAhead is how many years ahead of now a release may be announced
+	maxReleaseYearAhead = 10
+	maxRating           = 10
+	maxMetascore        = 100
+	// maxRuntime is the longest runtime accepted, in minutes
+	maxRuntime        = 1440
+	releaseDateFormat = "2006-01-02"
+)
+
+var (
+	yearRegexp   = regexp.MustCompile(`^\d{4}$`)
+	imdbIDRegexp = regexp.MustCompile(`^tt\d{7,}$`)
+)
+
+// releaseDateLayouts are the accepted release date formats, including OMDb's "06 May 2011"
+var releaseDateLayouts = []string{releaseDateFormat, "02 Jan 2006", "2 Jan 2006", "Jan 2, 2006", "January 2, 2006"}
+
+// notAvailable reports whether the value is empty or the N/A placeholder used by OMDb
+func notAvailable(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, "N/A")
+}
+
+// parseReleasedYear parses a 4 digit year, or a full release date from which the year is taken.
+// The release date is only returned in the latter case. Missing values are returned as nil
+func parseReleasedYear(value string) (interface{}, interface{}, error) {
+	if notAvailable(value) {
+		return nil, nil, nil
+	}
+	value = strings.TrimSpace(value)
+
+	if yearRegexp.MatchString(value) {
+		year, _ := strconv.Atoi(value)
+		if err := validateYear(year); err != nil {
+			return nil, nil, err
+		}
+		return year, nil, nil
+	}
+
+	for _, layout := range releaseDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if err := validateYear(t.Year()); err != nil {
+				return nil, nil, err
+			}
+			return t.Year(), t.Format(releaseDateFormat), nil
+		}
+	}
+
+	return nil, nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("ReleasedYear %q is not a year or a release date", value))
+}
+
+func validateYear(year int) error {
+	maxYear := time.Now().Year() + maxReleaseYearAhead
+	if year < minReleaseYear || year > maxYear {
+		return errors.Wrap(errs.ErrInvalid, fmt.Sprintf("release year %d must be between %d and %d", year, minReleaseYear, maxYear))
+	}
+	return nil
+}
+
+// parseReleaseDate returns the release date column value, nil when missing
+func parseReleaseDate(date *strfmt.Date) (interface{}, error) {
+	if date == nil || time.Time(*date).IsZero() {
+		return nil, nil
+	}
+	if err := validateYear(time.Time(*date).Year()); err != nil {
+		return nil, err
+	}
+	return time.Time(*date).Format(releaseDateFormat), nil
+}
+
+// parseRating parses a rating score between 0 and 10, rounded to one decimal
+func parseRating(value string) (interface{}, error) {
+	if notAvailable(value) {
+		return nil, nil
+	}
+
+	score, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(score) || score < 0 || score > maxRating {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Rating %q must be a number between 0 and %d", value, maxRating))
+	}
+	return math.Round(score*10) / 10, nil
+}
+
+// parseMetascore validates a Metascore between 0 and 100
+func parseMetascore(value *int64) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if *value < 0 || *value > maxMetascore {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Metascore %d must be between 0 and %d", *value, maxMetascore))
+	}
+	return *value, nil
+}
+
+// parseRuntime validates a runtime in minutes
+func parseRuntime(value *int64) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if *value < 1 || *value > maxRuntime {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Runtime %d must be between 1 and %d minutes", *value, maxRuntime))
+	}
+	return *value, nil
+}
+
+// parsePoster validates the poster URL, which must be an absolute http or https URL
+func parsePoster(value string) (interface{}, error) {
+	if notAvailable(value) {
+		return nil, nil
+	}
+	value = strings.TrimSpace(value)
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Poster %q must be an http or https URL", value))
+	}
+	return value, nil
+}
+
+// parseImdbID validates an IMDb ID such as tt1375666
+func parseImdbID(value string) (interface{}, error) {
+	if notAvailable(value) {
+		return nil, nil
+	}
+	value = strings.TrimSpace(value)
+	if !imdbIDRegexp.MatchString(value) {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("ImdbID %q must be tt followed by at least 7 digits", value))
+	}
+	return value, nil
+}
+
+// detailText returns the column value of a descriptive text such as the plot, nil when missing
+func detailText(value string) interface{} {
+	if notAvailable(value) {
+		return nil
+	}
+	return strings.TrimSpace(value)
+}
+
+// detailList returns the column value of a list such as the actors, the blank names dropped
+func detailList(values []string) pq.StringArray {
+	list := pq.StringArray{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); !notAvailable(value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// movieDetails are the descriptive fields of a movie as given to the API
+type movieDetails struct {
+	Plot     string
+	Runtime  *int64
+	Director string
+	Writers  []string
+	Actors   []string
+	Language string
+	Country  string
+	Poster   string
+	ImdbID   string
+}
+
+// detailFields validates the descriptive fields and returns them as column values
+func detailFields(in movieDetails) (map[string]interface{}, error) {
+	runtime, err := parseRuntime(in.Runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	poster, err := parsePoster(in.Poster)
+	if err != nil {
+		return nil, err
+	}
+
+	imdbID, err := parseImdbID(in.ImdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"plot":            detailText(in.Plot),
+		"runtime_minutes": runtime,
+		"director":        detailText(in.Director),
+		"writers":         detailList(in.Writers),
+		"actors":          detailList(in.Actors),
+		"language":        detailText(in.Language),
+		"country":         detailText(in.Country),
+		"poster_url":      poster,
+		"imdb_id":         imdbID,
+	}, nil
+}
+
+// typedFields validates the release and score values and returns them as column values. An
+// explicit release date takes precedence over the date given as the released year
+func typedFields(releasedYear string, releaseDate *strfmt.Date, rating string, metascore *int64) (map[string]interface{}, error) {
+	year, date, err := parseReleasedYear(releasedYear)
+	if err != nil {
+		return nil, err
+	}
+
+	explicitDate, err := parseReleaseDate(releaseDate)
+	if err != nil {
+		return nil, err
+	}
+	if explicitDate != nil {
+		date = explicitDate
+	}
+
+	score, err := parseRating(rating)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := parseMetascore(metascore)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"release_year": year,
+		"release_date": date,
+		"rating_score": score,
+		"metascore":    meta,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package movie
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+func TestParseReleasedYear(t *testing.T) {
+	tooLate := fmt.Sprint(time.Now().Year() + maxReleaseYearAhead + 1)
+	tests := []struct {
+		value   string
+		year    interface{}
+		date    interface{}
+		wantErr bool
+	}{
+		{value: ""},
+		{value: " N/A "},
+		{value: "2011", year: 2011},
+		{value: " 1888 ", year: 1888},
+		{value: "2011-05-06", year: 2011, date: "2011-05-06"},
+		{value: "06 May 2011", year: 2011, date: "2011-05-06"},
+		{value: "6 May 2011", year: 2011, date: "2011-05-06"},
+		{value: "May 6, 2011", year: 2011, date: "2011-05-06"},
+		{value: "1887", wantErr: true},
+		{value: tooLate, wantErr: true},
+		{value: "1887-12-31", wantErr: true},
+		{value: "2011–2019", wantErr: true},
+		{value: "05/06/2011", wantErr: true},
+		{value: "twenty eleven", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		year, date, err := parseReleasedYear(tt.value)
+		if tt.wantErr {
+			if errors.Cause(err) != errs.ErrInvalid {
+				t.Errorf("parseReleasedYear(%q) = %v, %v, %v, want errs.ErrInvalid", tt.value, year, date, err)
+			}
+			continue
+		}
+		if err != nil || year != tt.year || date != tt.date {
+			t.Errorf("parseReleasedYear(%q) = %v, %v, %v, want %v, %v", tt.value, year, date, err, tt.year, tt.date)
+		}
+	}
+}
+
+func TestParseRating(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "n/a"},
+		{value: "7.5", want: 7.5},
+		{value: " 8 ", want: 8.0},
+		{value: "7.46", want: 7.5},
+		{value: "7.44", want: 7.4},
+		{value: "0", want: 0.0},
+		{value: "10", want: 10.0},
+		{value: "10.01", wantErr: true},
+		{value: "-0.1", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "7,5", wantErr: true},
+		{value: "high", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRating(tt.value)
+		if tt.wantErr {
+			if errors.Cause(err) != errs.ErrInvalid {
+				t.Errorf("parseRating(%q) = %v, %v, want errs.ErrInvalid", tt.value, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseRating(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
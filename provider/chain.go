@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// The metadata fields, named as the movie fields they are stored in
+const (
+	FieldTitle        = "Title"
+	FieldReleasedYear = "ReleasedYear"
+	FieldReleaseDate  = "ReleaseDate"
+	FieldGenres       = "Genres"
+	FieldRating       = "Rating"
+	FieldMetascore    = "Metascore"
+	FieldPlot         = "Plot"
+	FieldRuntime      = "Runtime"
+	FieldDirector     = "Director"
+	FieldWriters      = "Writers"
+	FieldActors       = "Actors"
+	FieldLanguage     = "Language"
+	FieldCountry      = "Country"
+	FieldPoster       = "Poster"
+	FieldImdbID       = "ImdbID"
+)
+
+// DefaultRequiredFields are the fields the chain looks up until one of the providers has supplied them
+var DefaultRequiredFields = []string{FieldTitle, FieldReleasedYear, FieldGenres, FieldRating}
+
+// Source records the provider which supplied a field, and when
+type Source struct {
+	Provider  string
+	FetchedAt time.Time
+}
+
+type chain struct {
+	providers []MetadataProvider
+	required  []string
+}
+
+// NewChain creates a provider querying the providers in order until the required fields are
+// filled. The results are merged field by field, the first provider supplying a field wins. The
+// results of another film than the first one, by IMDb ID, are not merged
+func NewChain(required []string, providers ...MetadataProvider) MetadataProvider {
+	return &chain{
+		providers: providers,
+		required:  required,
+	}
+}
+
+func (c *chain) Name() string {
+	name := "chain"
+	for i, p := range c.providers {
+		if i == 0 {
+			name += ":"
+		} else {
+			name += ","
+		}
+		name += p.Name()
+	}
+	return name
+}
+
+// Statuses returns the state of the circuit breakers of the providers
+func (c *chain) Statuses() []Status {
+	statuses := []Status{}
+	for _, p := range c.providers {
+		statuses = append(statuses, Statuses(p)...)
+	}
+	return statuses
+}
+
+// MovieByTitle returns the merged metadata with the source of each field. It returns
+// errs.ErrNotFound when no provider knows the movie, or the last error when they all failed
+func (c *chain) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	var merged *MovieMetadata
+	var lastErr error
+
+	for _, p := range c.providers {
+		metadata, err := p.MovieByTitle(ctx, title)
+		if errors.Cause(err) == errs.ErrNotFound {
+			continue
+		}
+		if errors.Cause(err) == ErrUnavailable {
+			logrus.Debugf("metadata provider %s skipped for %q: %v", p.Name(), title, err)
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			logrus.Warnf("metadata provider %s failed to look up %q: %v", p.Name(), title, err)
+			lastErr = err
+			continue
+		}
+
+		// the lookup of a known movie skips the other films of its title
+		if identity := IdentityOf(ctx); !(&MovieMetadata{ImdbID: identity.ImdbID}).sameMovie(metadata) {
+			logrus.Debugf("metadata provider %s skipped for %q: IMDb ID %s, not %s", p.Name(), title, metadata.ImdbID, identity.ImdbID)
+			continue
+		}
+		if merged == nil {
+			merged = &MovieMetadata{Sources: make(map[string]Source)}
+		}
+		// the providers may resolve the title to different films, e.g. other releases of it
+		if !merged.sameMovie(metadata) {
+			logrus.Debugf("metadata provider %s skipped for %q: IMDb ID %s, not %s", p.Name(), title, metadata.ImdbID, merged.ImdbID)
+			continue
+		}
+		merged.merge(metadata, Source{Provider: p.Name(), FetchedAt: time.Now().UTC()})
+
+		if merged.complete(c.required) {
+			break
+		}
+	}
+
+	if merged != nil {
+		return merged, nil
+	}
+	if lastErr != nil {
+		return nil, errors.Wrap(lastErr, "chain.MovieByTitle")
+	}
+	return nil, errors.Wrap(errs.ErrNotFound, "chain.MovieByTitle")
+}
+
+// sameMovie reports whether from may be the same film, i.e. their IMDb IDs are equal or one is unknown
+func (m *MovieMetadata) sameMovie(from *MovieMetadata) bool {
+	return m.ImdbID == "" || from.ImdbID == "" || m.ImdbID == from.ImdbID
+}
+
+// merge fills the empty fields with the values of from, recording their source
+func (m *MovieMetadata) merge(from *MovieMetadata, source Source) {
+	if m.Title == "" && from.Title != "" {
+		m.Title = from.Title
+		m.Sources[FieldTitle] = source
+	}
+	if m.Year == "" && from.Year != "" {
+		m.Year = from.Year
+		m.Sources[FieldReleasedYear] = source
+	}
+	if m.Released == "" && from.Released != "" {
+		m.Released = from.Released
+		m.Sources[FieldReleaseDate] = source
+	}
+	if len(m.Genres) == 0 && len(from.Genres) != 0 {
+		m.Genres = append([]string{}, from.Genres...)
+		m.Sources[FieldGenres] = source
+	}
+	if m.Rating == "" && from.Rating != "" {
+		m.Rating = from.Rating
+		m.Sources[FieldRating] = source
+	}
+	if m.Metascore == nil && from.Metascore != nil {
+		m.Metascore = int64Ptr(*from.Metascore)
+		m.Sources[FieldMetascore] = source
+	}
+	if m.Plot == "" && from.Plot != "" {
+		m.Plot = from.Plot
+		m.Sources[FieldPlot] = source
+	}
+	if m.Runtime == nil && from.Runtime != nil {
+		m.Runtime = int64Ptr(*from.Runtime)
+		m.Sources[FieldRuntime] = source
+	}
+	if m.Director == "" && from.Director != "" {
+		m.Director = from.Director
+		m.Sources[FieldDirector] = source
+	}
+	if len(m.Writers) == 0 && len(from.Writers) != 0 {
+		m.Writers = append([]string{}, from.Writers...)
+		m.Sources[FieldWriters] = source
+	}
+	if len(m.Actors) == 0 && len(from.Actors) != 0 {
+		m.Actors = append([]string{}, from.Actors...)
+		m.Sources[FieldActors] = source
+	}
+	if m.Language == "" && from.Language != "" {
+		m.Language = from.Language
+		m.Sources[FieldLanguage] = source
+	}
+	if m.Country == "" && from.Country != "" {
+		m.Country = from.Country
+		m.Sources[FieldCountry] = source
+	}
+	if m.Poster == "" && from.Poster != "" {
+		m.Poster = from.Poster
+		m.Sources[FieldPoster] = source
+	}
+	if m.ImdbID == "" && from.ImdbID != "" {
+		m.ImdbID = from.ImdbID
+		m.Sources[FieldImdbID] = source
+	}
+}
+
+func knownField(field string) bool {
+	switch field {
+	case FieldTitle, FieldReleasedYear, FieldReleaseDate, FieldGenres, FieldRating, FieldMetascore,
+		FieldPlot, FieldRuntime, FieldDirector, FieldWriters, FieldActors, FieldLanguage, FieldCountry, FieldPoster, FieldImdbID:
+		return true
+	}
+	return false
+}
+
+// complete reports whether all the fields are filled
+func (m *MovieMetadata) complete(fields []string) bool {
+	for _, field := range fields {
+		if _, ok := m.Sources[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+func TestMerge(t *testing.T) {
+	omdb := Source{Provider: OMDb, FetchedAt: time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)}
+	rapid := Source{Provider: RapidAPI, FetchedAt: time.Date(2020, time.March, 1, 12, 0, 1, 0, time.UTC)}
+
+	tests := []struct {
+		name     string
+		results  []*MovieMetadata
+		want     *MovieMetadata
+		complete bool
+	}{
+		{
+			name:    "first provider wins",
+			results: []*MovieMetadata{{Title: "Dune", Year: "2021", Rating: "8.0"}, {Title: "DUNE", Year: "2020", Rating: "7.9"}},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", Rating: "8.0", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldRating: omdb,
+			}},
+		},
+		{
+			name:    "later provider fills the missing fields",
+			results: []*MovieMetadata{{Title: "Dune", Year: "2021"}, {Title: "Dune", Genres: []string{"Sci-Fi"}, Rating: "8.0"}},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", Genres: []string{"Sci-Fi"}, Rating: "8.0", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldGenres: rapid, FieldRating: rapid,
+			}},
+			complete: true,
+		},
+		{
+			name: "same IMDb ID",
+			results: []*MovieMetadata{
+				{Title: "Dune", Year: "2021", ImdbID: "tt1160419"},
+				{Title: "Dune", Genres: []string{"Sci-Fi"}, Rating: "8.0", Plot: "Paul Atreides", ImdbID: "tt1160419"},
+			},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", Genres: []string{"Sci-Fi"}, Rating: "8.0", Plot: "Paul Atreides", ImdbID: "tt1160419", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldImdbID: omdb, FieldGenres: rapid, FieldRating: rapid, FieldPlot: rapid,
+			}},
+			complete: true,
+		},
+		{
+			name: "another film of the title is not merged",
+			results: []*MovieMetadata{
+				{Title: "Dune", Year: "2021", ImdbID: "tt1160419"},
+				{Title: "Dune", Year: "1984", Genres: []string{"Adventure"}, Rating: "6.3", Plot: "A Duke's son", ImdbID: "tt0087182"},
+			},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", ImdbID: "tt1160419", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldImdbID: omdb,
+			}},
+		},
+		{
+			name: "result without IMDb ID",
+			results: []*MovieMetadata{
+				{Title: "Dune", Year: "2021", ImdbID: "tt1160419"},
+				{Title: "Dune", Genres: []string{"Sci-Fi"}, Rating: "8.0"},
+			},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", Genres: []string{"Sci-Fi"}, Rating: "8.0", ImdbID: "tt1160419", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldImdbID: omdb, FieldGenres: rapid, FieldRating: rapid,
+			}},
+			complete: true,
+		},
+		{
+			name: "first result without IMDb ID",
+			results: []*MovieMetadata{
+				{Title: "Dune", Year: "2021"},
+				{Title: "Dune", Rating: "8.0", ImdbID: "tt1160419"},
+			},
+			want: &MovieMetadata{Title: "Dune", Year: "2021", Rating: "8.0", ImdbID: "tt1160419", Sources: map[string]Source{
+				FieldTitle: omdb, FieldReleasedYear: omdb, FieldRating: rapid, FieldImdbID: rapid,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := &MovieMetadata{Sources: make(map[string]Source)}
+			for i, result := range tt.results {
+				if merged.sameMovie(result) {
+					merged.merge(result, []Source{omdb, rapid}[i])
+				}
+			}
+			if !reflect.DeepEqual(merged, tt.want) {
+				t.Errorf("got %+v\nwant %+v", merged, tt.want)
+			}
+			if got := merged.complete(DefaultRequiredFields); got != tt.complete {
+				t.Errorf("complete: got %v, want %v", got, tt.complete)
+			}
+		})
+	}
+}
+
+// staticProvider answers every lookup with its metadata, or err
+type staticProvider struct {
+	name     string
+	metadata *MovieMetadata
+	err      error
+	calls    int
+}
+
+func (p *staticProvider) Name() string {
+	return p.name
+}
+
+func (p *staticProvider) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	metadata := *p.metadata
+	return &metadata, nil
+}
+
+func TestChain(t *testing.T) {
+	complete := &MovieMetadata{Title: "Dune", Year: "2021", Genres: []string{"Sci-Fi"}, Rating: "8.0", ImdbID: "tt1160419"}
+	partial := &MovieMetadata{Title: "Dune", Year: "2021", ImdbID: "tt1160419"}
+	otherFilm := &MovieMetadata{Title: "Dune", Year: "1984", Genres: []string{"Adventure"}, Rating: "6.3", ImdbID: "tt0087182"}
+	failure := errors.New("provider down")
+
+	tests := []struct {
+		name      string
+		identity  Identity
+		providers []*staticProvider
+		wantErr   error
+		wantFrom  map[string]string
+		wantCalls []int
+	}{
+		{
+			name:      "stops once complete",
+			providers: []*staticProvider{{name: "a", metadata: complete}, {name: "b", metadata: complete}},
+			wantFrom:  map[string]string{FieldTitle: "a", FieldRating: "a"},
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "skips the not found and failed providers",
+			providers: []*staticProvider{{name: "a", err: errs.ErrNotFound}, {name: "b", err: failure}, {name: "c", metadata: complete}},
+			wantFrom:  map[string]string{FieldTitle: "c", FieldRating: "c"},
+			wantCalls: []int{1, 1, 1},
+		},
+		{
+			name:      "skips another film",
+			providers: []*staticProvider{{name: "a", metadata: partial}, {name: "b", metadata: otherFilm}, {name: "c", metadata: complete}},
+			wantFrom:  map[string]string{FieldTitle: "a", FieldReleasedYear: "a", FieldGenres: "c", FieldRating: "c"},
+			wantCalls: []int{1, 1, 1},
+		},
+		{
+			name:      "skips the films of another IMDb ID than the identity",
+			identity:  Identity{ImdbID: "tt0087182"},
+			providers: []*staticProvider{{name: "a", metadata: complete}, {name: "b", metadata: otherFilm}},
+			wantFrom:  map[string]string{FieldTitle: "b", FieldRating: "b"},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "not found",
+			providers: []*staticProvider{{name: "a", err: errs.ErrNotFound}, {name: "b", err: errs.ErrNotFound}},
+			wantErr:   errs.ErrNotFound,
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "last failure",
+			providers: []*staticProvider{{name: "a", err: failure}, {name: "b", err: errs.ErrNotFound}},
+			wantErr:   failure,
+			wantCalls: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := []MetadataProvider{}
+			for _, p := range tt.providers {
+				providers = append(providers, p)
+			}
+			metadata, err := NewChain(DefaultRequiredFields, providers...).MovieByTitle(WithIdentity(context.Background(), tt.identity), "Dune")
+			if errors.Cause(err) != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			for field, from := range tt.wantFrom {
+				if got := metadata.Sources[field].Provider; got != from {
+					t.Errorf("%s from %s, want %s", field, got, from)
+				}
+			}
+			for i, p := range tt.providers {
+				if p.calls != tt.wantCalls[i] {
+					t.Errorf("%s looked up %d times, want %d", p.name, p.calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+// FakeMovies are the movies known to the fake provider selected by config
+var FakeMovies = []MovieMetadata{
+	{Title: "Inception", Year: "2010", Released: "16 Jul 2010", Genres: []string{"Action", "Adventure", "Sci-Fi"}, Rating: "8.8", Metascore: int64Ptr(74),
+		Runtime: int64Ptr(148), Director: "Christopher Nolan", Writers: []string{"Christopher Nolan"},
+		Actors: []string{"Leonardo DiCaprio", "Joseph Gordon-Levitt", "Elliot Page"}, Language: "English, Japanese, French",
+		Country: "United States, United Kingdom", ImdbID: "tt1375666",
+		Plot: "A thief who steals corporate secrets through the use of dream-sharing technology is given the inverse task of planting an idea into the mind of a C.E.O."},
+	{Title: "Thor", Year: "2011", Released: "06 May 2011", Genres: []string{"Action", "Adventure", "Fantasy"}, Rating: "7.0", Metascore: int64Ptr(57),
+		Runtime: int64Ptr(115), Director: "Kenneth Branagh", Writers: []string{"Ashley Miller", "Zack Stentz", "Don Payne"},
+		Actors: []string{"Chris Hemsworth", "Anthony Hopkins", "Natalie Portman"}, Language: "English",
+		Country: "United States", ImdbID: "tt0800369",
+		Plot: "The powerful but arrogant god Thor is cast out of Asgard to live amongst humans in Midgard (Earth), where he soon becomes one of their finest defenders."},
+	{Title: "The Matrix", Year: "1999", Released: "31 Mar 1999", Genres: []string{"Action", "Sci-Fi"}, Rating: "8.7", Metascore: int64Ptr(73),
+		Runtime: int64Ptr(136), Director: "Lana Wachowski, Lilly Wachowski", Writers: []string{"Lilly Wachowski", "Lana Wachowski"},
+		Actors: []string{"Keanu Reeves", "Laurence Fishburne", "Carrie-Anne Moss"}, Language: "English",
+		Country: "United States, Australia", ImdbID: "tt0133093",
+		Plot: "When a beautiful stranger leads computer hacker Neo to a forbidding underworld, he discovers the shocking truth--the life he knows is the elaborate deception of an evil cyber-intelligence."},
+}
+
+type fake struct {
+	movies map[string]MovieMetadata
+}
+
+// NewFake creates a deterministic in memory provider knowing only the specified movies,
+// which are matched by their case insensitive title
+func NewFake(movies ...MovieMetadata) MetadataProvider {
+	p := &fake{
+		movies: make(map[string]MovieMetadata),
+	}
+	for _, movie := range movies {
+		p.movies[fakeKey(movie.Title)] = movie
+	}
+	return p
+}
+
+func (p *fake) Name() string {
+	return Fake
+}
+
+// MovieByTitle returns a copy of the known movie
+func (p *fake) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	movie, ok := p.movies[fakeKey(title)]
+	if !ok {
+		return nil, errors.Wrap(errs.ErrNotFound, "fake.MovieByTitle")
+	}
+
+	movie.Genres = append([]string{}, movie.Genres...)
+	movie.Writers = append([]string{}, movie.Writers...)
+	movie.Actors = append([]string{}, movie.Actors...)
+	if movie.Metascore != nil {
+		movie.Metascore = int64Ptr(*movie.Metascore)
+	}
+	if movie.Runtime != nil {
+		movie.Runtime = int64Ptr(*movie.Runtime)
+	}
+	return &movie, nil
+}
+
+func fakeKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,349 @@
+package provider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnavailable is returned for the lookups a provider does not make, because its rate limit is
+// exhausted or its circuit breaker is open
+var ErrUnavailable = errors.New("metadata provider unavailable")
+
+// The circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Limits protects the lookups of a provider
+type Limits struct {
+	// Rate is the quota of the provider, such as "1000/day", "10/min" or "5/s", unlimited when empty
+	Rate string
+	// Burst is the number of lookups allowed at once, the token bucket capacity
+	Burst int
+	// Timeout bounds the duration of a lookup
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failures opening the circuit breaker
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before a trial lookup
+	OpenDuration time.Duration
+}
+
+// DefaultLimits are the limits used for the values not set
+var DefaultLimits = Limits{
+	Burst:            10,
+	Timeout:          5 * time.Second,
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+// Status is the state of the circuit breaker of a provider
+type Status struct {
+	Provider string
+	State    string
+	// Failures is the number of consecutive failures
+	Failures int
+	// OpenUntil is the time of the next trial lookup of an open breaker
+	OpenUntil time.Time
+}
+
+// Degraded reports whether the provider is not looked up
+func (s Status) Degraded() bool {
+	return s.State == BreakerOpen
+}
+
+// StatusReporter is implemented by the providers reporting the state of their circuit breakers
+type StatusReporter interface {
+	Statuses() []Status
+}
+
+// Statuses returns the state of the circuit breakers of the provider, none when it has no breaker
+func Statuses(p MetadataProvider) []Status {
+	if reporter, ok := p.(StatusReporter); ok {
+		return reporter.Statuses()
+	}
+	return nil
+}
+
+// Available reports whether the provider may be looked up, i.e. the circuit breaker of at least one of
+// its providers is not open
+func Available(p MetadataProvider) bool {
+	if p == nil {
+		return false
+	}
+	statuses := Statuses(p)
+	for _, status := range statuses {
+		if !status.Degraded() {
+			return true
+		}
+	}
+	return len(statuses) == 0
+}
+
+type waitKey struct{}
+
+// WaitForQuota lets the lookups of the context wait for the rate limit, up to the context deadline,
+// instead of failing right away, e.g. in background jobs
+func WaitForQuota(ctx context.Context) context.Context {
+	return context.WithValue(ctx, waitKey{}, true)
+}
+
+func waitsForQuota(ctx context.Context) bool {
+	wait, _ := ctx.Value(waitKey{}).(bool)
+	return wait
+}
+
+// clock is the time of the rate limiters and the circuit breakers, replaced by the tests
+type clock struct {
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+var systemClock = clock{now: time.Now, after: time.After}
+
+type guarded struct {
+	next   MetadataProvider
+	limits Limits
+	bucket *tokenBucket
+	clock  clock
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// Guard protects the lookups of the provider with a token bucket rate limiter, a timeout and a
+// circuit breaker opening after consecutive failures. The lookups it does not make fail with
+// ErrUnavailable
+func Guard(p MetadataProvider, limits Limits) (MetadataProvider, error) {
+	if limits.Burst <= 0 {
+		limits.Burst = DefaultLimits.Burst
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultLimits.Timeout
+	}
+	if limits.FailureThreshold <= 0 {
+		limits.FailureThreshold = DefaultLimits.FailureThreshold
+	}
+	if limits.OpenDuration <= 0 {
+		limits.OpenDuration = DefaultLimits.OpenDuration
+	}
+
+	g := &guarded{next: p, limits: limits, clock: systemClock}
+	if strings.TrimSpace(limits.Rate) != "" {
+		perSecond, err := parseRate(limits.Rate)
+		if err != nil {
+			return nil, errors.Wrap(err, p.Name())
+		}
+		g.bucket = newTokenBucket(perSecond, limits.Burst, g.clock)
+	}
+	return g, nil
+}
+
+func (g *guarded) Name() string {
+	return g.next.Name()
+}
+
+func (g *guarded) Statuses() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return []Status{g.status(g.clock.now())}
+}
+
+// status returns the state of the breaker, the caller holds the lock
+func (g *guarded) status(now time.Time) Status {
+	status := Status{Provider: g.next.Name(), State: BreakerClosed, Failures: g.failures}
+	switch {
+	case g.openUntil.IsZero():
+	case now.Before(g.openUntil) || g.trial:
+		status.State = BreakerOpen
+		status.OpenUntil = g.openUntil
+	default:
+		status.State = BreakerHalfOpen
+	}
+	return status
+}
+
+// MovieByTitle looks up the movie unless the breaker is open or the rate limit exhausted
+func (g *guarded) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	if err := g.allow(); err != nil {
+		return nil, err
+	}
+
+	if g.bucket != nil {
+		if err := g.bucket.take(ctx, waitsForQuota(ctx)); err != nil {
+			g.release()
+			return nil, errors.Wrap(err, g.next.Name())
+		}
+	}
+
+	metadata, err := g.lookup(ctx, title)
+	g.record(ctx, err)
+	return metadata, err
+}
+
+// allow fails while the breaker is open. Once OpenDuration has passed a single trial lookup is let
+// through, the half-open state
+func (g *guarded) allow() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status := g.status(g.clock.now())
+	switch status.State {
+	case BreakerOpen:
+		return errors.Wrapf(ErrUnavailable, "%s circuit open until %s", g.next.Name(), status.OpenUntil.Format(time.RFC3339))
+	case BreakerHalfOpen:
+		g.trial = true
+	}
+	return nil
+}
+
+// release gives the trial lookup back when it was not made
+func (g *guarded) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.trial = false
+}
+
+// lookup looks up the movie within the timeout. The clients of the providers do not take a context,
+// the lookup is abandoned, not stopped, when it times out
+func (g *guarded) lookup(ctx context.Context, title string) (*MovieMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.limits.Timeout)
+	defer cancel()
+
+	type result struct {
+		metadata *MovieMetadata
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		metadata, err := g.next.MovieByTitle(ctx, title)
+		done <- result{metadata: metadata, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.metadata, r.err
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "%s lookup of %q", g.next.Name(), title)
+	}
+}
+
+// record counts the consecutive failures, opening the breaker at the threshold. A not found answer
+// is a success, and a lookup canceled by the caller is neither
+func (g *guarded) record(ctx context.Context, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	trial := g.trial
+	g.trial = false
+
+	if err == nil || errors.Cause(err) == errs.ErrNotFound {
+		if !g.openUntil.IsZero() {
+			logrus.Infof("metadata provider %s circuit closed", g.next.Name())
+		}
+		g.failures = 0
+		g.openUntil = time.Time{}
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	g.failures++
+	if trial || g.failures >= g.limits.FailureThreshold {
+		g.openUntil = g.clock.now().Add(g.limits.OpenDuration)
+		logrus.Warnf("metadata provider %s circuit open until %s after %d consecutive failures: %v",
+			g.next.Name(), g.openUntil.Format(time.RFC3339), g.failures, err)
+	}
+}
+
+// tokenBucket allows perSecond lookups on average, and up to burst at once
+type tokenBucket struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	tokens    float64
+	updated   time.Time
+	clock     clock
+}
+
+func newTokenBucket(perSecond float64, burst int, clock clock) *tokenBucket {
+	return &tokenBucket{
+		perSecond: perSecond,
+		burst:     float64(burst),
+		tokens:    float64(burst),
+		updated:   clock.now(),
+		clock:     clock,
+	}
+}
+
+// take takes a token. Without one it fails with ErrUnavailable, or with wait sleeps until the next
+// token when it comes before the context deadline
+func (b *tokenBucket) take(ctx context.Context, wait bool) error {
+	b.mu.Lock()
+	now := b.clock.now()
+	b.tokens += now.Sub(b.updated).Seconds() * b.perSecond
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.mu.Unlock()
+		return nil
+	}
+
+	delay := time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+	deadline, ok := ctx.Deadline()
+	if !wait || (ok && now.Add(delay).After(deadline)) {
+		b.mu.Unlock()
+		return errors.Wrap(ErrUnavailable, "rate limit exhausted")
+	}
+	// the token is reserved, the lookups waiting after this one wait longer
+	b.tokens--
+	b.mu.Unlock()
+
+	select {
+	case <-b.clock.after(delay):
+		return nil
+	case <-ctx.Done():
+		// the lookup is not made, the reserved token goes back to the bucket
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// parseRate parses a quota such as "1000/day" into lookups per second
+func parseRate(rate string) (float64, error) {
+	parts := strings.SplitN(strings.TrimSpace(rate), "/", 2)
+	if len(parts) != 2 {
+		return 0, errors.Errorf("invalid rate %q, expected <count>/<s|min|hour|day>", rate)
+	}
+	count, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || count <= 0 {
+		return 0, errors.Errorf("invalid rate %q, the count must be a positive number", rate)
+	}
+
+	units := map[string]time.Duration{
+		"s": time.Second, "sec": time.Second, "second": time.Second,
+		"min": time.Minute, "minute": time.Minute,
+		"h": time.Hour, "hour": time.Hour,
+		"day": 24 * time.Hour,
+	}
+	unit, ok := units[strings.ToLower(strings.TrimSpace(parts[1]))]
+	if !ok {
+		return 0, errors.Errorf("invalid rate %q, unknown unit %q", rate, parts[1])
+	}
+	return count / unit.Seconds(), nil
+}
@@ -0,0 +1,365 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+// fakeClock is a clock moved forward by the tests, its timers fire when it passes them
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) clock() clock {
+	return clock{now: c.Now, after: c.After}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	return timer.c
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.now
+	}
+	c.timers = pending
+}
+
+// waitForTimer waits until a timer is set, i.e. a take is waiting for its token
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		c.mu.Lock()
+		n := len(c.timers)
+		c.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no take waiting for a token")
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	clock := newFakeClock()
+	bucket := newTokenBucket(1, 2, clock.clock())
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		wantErr bool
+	}{
+		{name: "first of the burst"},
+		{name: "second of the burst"},
+		{name: "burst exhausted", wantErr: true},
+		{name: "half a token refilled", advance: 500 * time.Millisecond, wantErr: true},
+		{name: "a token refilled", advance: 500 * time.Millisecond},
+		{name: "refill capped at the burst", advance: time.Minute},
+		{name: "second token of the capped refill"},
+		{name: "capped refill exhausted", wantErr: true},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		err := bucket.take(ctx, false)
+		if step.wantErr {
+			if errors.Cause(err) != ErrUnavailable {
+				t.Fatalf("%s: got %v, want ErrUnavailable", step.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", step.name, err)
+		}
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := newFakeClock()
+	bucket := newTokenBucket(1, 1, clock.clock())
+	if err := bucket.take(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bucket.take(context.Background(), true)
+	}()
+	clock.waitForTimer(t)
+
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("take returned %v before the token was refilled", err)
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the token taken by the wait is used, the next one comes a second later
+	if err := bucket.take(context.Background(), false); errors.Cause(err) != ErrUnavailable {
+		t.Fatalf("got %v, want ErrUnavailable", err)
+	}
+}
+
+func TestTokenBucketWaitBeyondDeadline(t *testing.T) {
+	clock := newFakeClock()
+	bucket := newTokenBucket(1, 1, clock.clock())
+	if err := bucket.take(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(500*time.Millisecond))
+	defer cancel()
+	if err := bucket.take(ctx, true); errors.Cause(err) != ErrUnavailable {
+		t.Fatalf("got %v, want ErrUnavailable", err)
+	}
+
+	// the rejected take reserved no token
+	clock.Advance(time.Second)
+	if err := bucket.take(context.Background(), false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTokenBucketWaitCanceled(t *testing.T) {
+	clock := newFakeClock()
+	bucket := newTokenBucket(1, 1, clock.clock())
+	if err := bucket.take(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bucket.take(ctx, true)
+	}()
+	clock.waitForTimer(t)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// the reserved token was given back, so the next token comes a second later, not two
+	clock.Advance(time.Second)
+	if err := bucket.take(context.Background(), false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// stubProvider answers the lookups with err, counting them
+type stubProvider struct {
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &MovieMetadata{Title: title}, nil
+}
+
+func (p *stubProvider) answer(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *stubProvider) lookups() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	clock := newFakeClock()
+	stub := &stubProvider{}
+	p, err := Guard(stub, Limits{FailureThreshold: 2, OpenDuration: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p.(*guarded)
+	g.clock = clock.clock()
+	ctx := context.Background()
+	failure := errors.New("provider down")
+
+	lookup := func() error {
+		_, err := g.MovieByTitle(ctx, "Thor")
+		return err
+	}
+	state := func() string {
+		return g.Statuses()[0].State
+	}
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		answer    error
+		wantErr   error
+		wantState string
+		wantCalls int
+	}{
+		{name: "success", wantState: BreakerClosed, wantCalls: 1},
+		{name: "first failure", answer: failure, wantErr: failure, wantState: BreakerClosed, wantCalls: 2},
+		{name: "not found is a success", answer: errs.ErrNotFound, wantErr: errs.ErrNotFound, wantState: BreakerClosed, wantCalls: 3},
+		{name: "failure after a success", answer: failure, wantErr: failure, wantState: BreakerClosed, wantCalls: 4},
+		{name: "threshold opens", answer: failure, wantErr: failure, wantState: BreakerOpen, wantCalls: 5},
+		{name: "open skips the lookup", wantErr: ErrUnavailable, wantState: BreakerOpen, wantCalls: 5},
+		{name: "still open", advance: 29 * time.Second, wantErr: ErrUnavailable, wantState: BreakerOpen, wantCalls: 5},
+		{name: "failed trial opens again", advance: time.Second, answer: failure, wantErr: failure, wantState: BreakerOpen, wantCalls: 6},
+		{name: "open after the failed trial", advance: 29 * time.Second, wantErr: ErrUnavailable, wantState: BreakerOpen, wantCalls: 6},
+		{name: "trial closes", advance: time.Second, wantState: BreakerClosed, wantCalls: 7},
+		{name: "closed", wantState: BreakerClosed, wantCalls: 8},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		stub.answer(step.answer)
+		err := lookup()
+		if errors.Cause(err) != step.wantErr {
+			t.Fatalf("%s: got error %v, want %v", step.name, err, step.wantErr)
+		}
+		if got := state(); got != step.wantState {
+			t.Fatalf("%s: got state %s, want %s", step.name, got, step.wantState)
+		}
+		if got := stub.lookups(); got != step.wantCalls {
+			t.Fatalf("%s: got %d lookups, want %d", step.name, got, step.wantCalls)
+		}
+	}
+}
+
+func TestBreakerHalfOpenSingleTrial(t *testing.T) {
+	clock := newFakeClock()
+	p, err := Guard(&stubProvider{err: errors.New("provider down")}, Limits{FailureThreshold: 1, OpenDuration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p.(*guarded)
+	g.clock = clock.clock()
+
+	if _, err := g.MovieByTitle(context.Background(), "Thor"); err == nil {
+		t.Fatal("expected the lookup to fail")
+	}
+	clock.Advance(time.Minute)
+	if got := g.Statuses()[0].State; got != BreakerHalfOpen {
+		t.Fatalf("got state %s, want %s", got, BreakerHalfOpen)
+	}
+
+	// the first caller takes the trial, the breaker is open for the others until it is recorded
+	if err := g.allow(); err != nil {
+		t.Fatalf("trial refused: %v", err)
+	}
+	if err := g.allow(); errors.Cause(err) != ErrUnavailable {
+		t.Fatalf("got %v, want ErrUnavailable during the trial", err)
+	}
+
+	// a trial given back without a lookup lets the next caller try
+	g.release()
+	if err := g.allow(); err != nil {
+		t.Fatalf("trial refused after release: %v", err)
+	}
+}
+
+func TestGuardRateLimitIsNotAFailure(t *testing.T) {
+	clock := newFakeClock()
+	stub := &stubProvider{}
+	p, err := Guard(stub, Limits{Rate: "1/min", Burst: 1, FailureThreshold: 1, OpenDuration: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p.(*guarded)
+	g.clock = clock.clock()
+	g.bucket = newTokenBucket(1.0/60, 1, g.clock)
+
+	if _, err := g.MovieByTitle(context.Background(), "Thor"); err != nil {
+		t.Fatal(err)
+	}
+	// rate limited lookups are not failures of the provider
+	for i := 0; i < 3; i++ {
+		if _, err := g.MovieByTitle(context.Background(), "Thor"); errors.Cause(err) != ErrUnavailable {
+			t.Fatalf("got %v, want ErrUnavailable", err)
+		}
+	}
+	if status := g.Statuses()[0]; status.State != BreakerClosed || status.Failures != 0 {
+		t.Fatalf("got %+v, want a closed breaker without failures", status)
+	}
+	if got := stub.lookups(); got != 1 {
+		t.Fatalf("got %d lookups, want 1", got)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate    string
+		want    float64
+		wantErr bool
+	}{
+		{rate: "5/s", want: 5},
+		{rate: "10/min", want: 10.0 / 60},
+		{rate: " 3600 / hour ", want: 1},
+		{rate: "1000/day", want: 1000.0 / 86400},
+		{rate: "1000", wantErr: true},
+		{rate: "0/s", wantErr: true},
+		{rate: "-1/s", wantErr: true},
+		{rate: "ten/s", wantErr: true},
+		{rate: "10/week", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRate(tt.rate)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRate(%q) = %v, want an error", tt.rate, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseRate(%q) = %v, %v, want %v", tt.rate, got, err, tt.want)
+		}
+	}
+}
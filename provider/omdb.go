@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	imdb "github.com/eefret/go-imdb"
+	"github.com/movieManagement/errs"
+	"github.com/pkg/errors"
+)
+
+type omdb struct {
+	api *imdb.OmdbApi
+}
+
+// NewOMDb creates the OMDb provider with the specified API key
+func NewOMDb(apiKey string) MetadataProvider {
+	return &omdb{
+		api: imdb.Init(apiKey),
+	}
+}
+
+func (p *omdb) Name() string {
+	return OMDb
+}
+
+// MovieByTitle looks up the movie with the OMDb title search, of the year of the context identity
+func (p *omdb) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	result, err := p.api.MovieByTitle(&imdb.QueryData{Title: title, Year: IdentityOf(ctx).Year})
+	if err != nil {
+		// OMDb answers an unknown title with the error "Movie not found!"
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return nil, errors.Wrap(errs.ErrNotFound, "omdb.MovieByTitle")
+		}
+		return nil, errors.Wrap(err, "omdb.MovieByTitle")
+	}
+	if result == nil {
+		return nil, errors.Wrap(errs.ErrNotFound, "omdb.MovieByTitle")
+	}
+
+	return &MovieMetadata{
+		Title:     notAvailable(result.Title),
+		Year:      notAvailable(result.Year),
+		Released:  notAvailable(result.Released),
+		Genres:    splitList(result.Genre),
+		Rating:    notAvailable(result.ImdbRating),
+		Metascore: parseMetascore(result.Metascore),
+		Plot:      notAvailable(result.Plot),
+		Runtime:   parseRuntime(result.Runtime),
+		Director:  notAvailable(result.Director),
+		Writers:   splitList(result.Writer),
+		Actors:    splitList(result.Actors),
+		Language:  notAvailable(result.Language),
+		Country:   notAvailable(result.Country),
+		Poster:    notAvailable(result.Poster),
+		ImdbID:    notAvailable(result.ImdbID),
+	}, nil
+}
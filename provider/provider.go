@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OMDb is the name of the OMDb provider, see http://www.omdbapi.com
+	OMDb = "omdb"
+	// RapidAPI is the name of the RapidAPI movie database provider
+	RapidAPI = "rapidapi"
+	// Fake is the name of the deterministic in memory provider
+	Fake = "fake"
+	// None disables the metadata lookups
+	None = "none"
+	// Manual is the provenance of the values edited by hand, which are never replaced by provider values
+	Manual = "manual"
+)
+
+// MovieMetadata is the movie metadata found by a provider. Values not available from the
+// provider are left empty, the year and release date are kept as formatted by the provider.
+// Runtime is in minutes. Sources is set by the chain, keyed by the Field constants
+type MovieMetadata struct {
+	Title     string
+	Year      string
+	Released  string
+	Genres    []string
+	Rating    string
+	Metascore *int64
+	Plot      string
+	Runtime   *int64
+	Director  string
+	Writers   []string
+	Actors    []string
+	Language  string
+	Country   string
+	Poster    string
+	ImdbID    string
+	Sources   map[string]Source
+}
+
+type freshKey struct{}
+
+// Fresh asks the lookups of the context to skip the caches, e.g. to refresh stale values
+func Fresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// IsFresh reports whether the lookups of the context skip the caches
+func IsFresh(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
+}
+
+// Identity narrows a lookup by title to the release year or the IMDb ID of the movie, when they
+// are known, e.g. for a refresh
+type Identity struct {
+	Year   string
+	ImdbID string
+}
+
+type identityKey struct{}
+
+// WithIdentity narrows the lookups of the context to the movie of the identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityOf returns the identity the lookups of the context are narrowed to, empty when none
+func IdentityOf(ctx context.Context) Identity {
+	identity, _ := ctx.Value(identityKey{}).(Identity)
+	return identity
+}
+
+// MetadataProvider looks up the metadata of movies missing from the database. MovieByTitle
+// returns errs.ErrNotFound when the provider does not know the movie
+type MetadataProvider interface {
+	Name() string
+	MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error)
+}
+
+// Config selects and configures the metadata providers. Providers is the comma separated,
+// ordered list of the providers to query and RequiredFields the comma separated fields
+// looked up until filled, DefaultRequiredFields when empty
+type Config struct {
+	Providers      string
+	RequiredFields string
+	OMDbAPIKey     string
+	OMDbLimits     Limits
+	RapidAPIKey    string
+	RapidAPIHost   string
+	RapidAPILimits Limits
+}
+
+// New returns the chain of the providers selected by the config. The providers missing their
+// API key are skipped, and a nil provider, disabling the lookups, is returned when none is left.
+// The external providers are guarded by their limits
+func New(cfg Config) (MetadataProvider, error) {
+	providers := []MetadataProvider{}
+	for _, name := range strings.Split(cfg.Providers, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case OMDb:
+			if cfg.OMDbAPIKey == "" {
+				logrus.Warnf("metadata provider %s skipped, its API key is not configured", name)
+				continue
+			}
+			guarded, err := Guard(NewOMDb(cfg.OMDbAPIKey), cfg.OMDbLimits)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, guarded)
+		case RapidAPI:
+			if cfg.RapidAPIKey == "" {
+				logrus.Warnf("metadata provider %s skipped, its API key is not configured", name)
+				continue
+			}
+			guarded, err := Guard(NewRapidAPI(cfg.RapidAPIHost, cfg.RapidAPIKey), cfg.RapidAPILimits)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, guarded)
+		case Fake:
+			providers = append(providers, NewFake(FakeMovies...))
+		case None, "":
+		default:
+			return nil, errors.Errorf("unknown metadata provider %q, expected %s, %s, %s or %s", name, OMDb, RapidAPI, Fake, None)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	required := []string{}
+	for _, field := range strings.Split(cfg.RequiredFields, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		if !knownField(field) {
+			return nil, errors.Errorf("unknown required metadata field %q", field)
+		}
+		required = append(required, field)
+	}
+	if len(required) == 0 {
+		required = DefaultRequiredFields
+	}
+	return NewChain(required, providers...), nil
+}
+
+// notAvailable maps the N/A placeholder used by OMDb to an empty value
+func notAvailable(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "N/A") {
+		return ""
+	}
+	return value
+}
+
+// splitList splits a comma separated list such as the genres "Action, Drama"
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(notAvailable(value), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRuntime returns the runtime in minutes formatted by OMDb as "148 min", nil when not available
+func parseRuntime(value string) *int64 {
+	runtime, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(notAvailable(value), "min")), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &runtime
+}
+
+// parseMetascore returns the Metascore, nil when not available
+func parseMetascore(value string) *int64 {
+	metascore, err := strconv.ParseInt(notAvailable(value), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &metascore
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/helper"
+	"github.com/pkg/errors"
+)
+
+// DefaultRapidAPIHost is the RapidAPI host of the movie database, answering in the OMDb format
+const DefaultRapidAPIHost = "movie-database-imdb-alternative.p.rapidapi.com"
+
+type rapidAPI struct {
+	http helper.HTTPService
+	host string
+	key  string
+}
+
+// rapidAPIMovie is the OMDb formatted movie returned by the RapidAPI movie database
+type rapidAPIMovie struct {
+	Title      string `json:"Title"`
+	Year       string `json:"Year"`
+	Released   string `json:"Released"`
+	Genre      string `json:"Genre"`
+	ImdbRating string `json:"imdbRating"`
+	Metascore  string `json:"Metascore"`
+	Plot       string `json:"Plot"`
+	Runtime    string `json:"Runtime"`
+	Director   string `json:"Director"`
+	Writer     string `json:"Writer"`
+	Actors     string `json:"Actors"`
+	Language   string `json:"Language"`
+	Country    string `json:"Country"`
+	Poster     string `json:"Poster"`
+	ImdbID     string `json:"imdbID"`
+	Response   string `json:"Response"`
+	Error      string `json:"Error"`
+}
+
+// NewRapidAPI creates the RapidAPI movie database provider with the specified host and API key
+func NewRapidAPI(host, apiKey string) MetadataProvider {
+	if host == "" {
+		host = DefaultRapidAPIHost
+	}
+	return &rapidAPI{
+		http: helper.NewHTTPService(),
+		host: host,
+		key:  apiKey,
+	}
+}
+
+func (p *rapidAPI) Name() string {
+	return RapidAPI
+}
+
+// MovieByTitle looks up the movie with the RapidAPI title search, or by the IMDb ID of the context
+// identity when it has one
+func (p *rapidAPI) MovieByTitle(ctx context.Context, title string) (*MovieMetadata, error) {
+	query := map[string]string{"t": title, "r": "json"}
+	identity := IdentityOf(ctx)
+	if identity.ImdbID != "" {
+		query = map[string]string{"i": identity.ImdbID, "r": "json"}
+	} else if identity.Year != "" {
+		query["y"] = identity.Year
+	}
+
+	res, err := p.http.GetWithHeaders(fmt.Sprintf("https://%s/", p.host), query,
+		map[string]string{"x-rapidapi-host": p.host, "x-rapidapi-key": p.key})
+	if err != nil {
+		return nil, errors.Wrap(err, "rapidapi.MovieByTitle")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("rapidapi.MovieByTitle: unexpected status %s", res.Status)
+	}
+
+	result := rapidAPIMovie{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, errors.Wrap(err, "rapidapi.MovieByTitle.Decode")
+	}
+	if !strings.EqualFold(result.Response, "True") {
+		if strings.Contains(strings.ToLower(result.Error), "not found") {
+			return nil, errors.Wrap(errs.ErrNotFound, "rapidapi.MovieByTitle")
+		}
+		return nil, errors.Errorf("rapidapi.MovieByTitle: %s", result.Error)
+	}
+
+	return &MovieMetadata{
+		Title:     notAvailable(result.Title),
+		Year:      notAvailable(result.Year),
+		Released:  notAvailable(result.Released),
+		Genres:    splitList(result.Genre),
+		Rating:    notAvailable(result.ImdbRating),
+		Metascore: parseMetascore(result.Metascore),
+		Plot:      notAvailable(result.Plot),
+		Runtime:   parseRuntime(result.Runtime),
+		Director:  notAvailable(result.Director),
+		Writers:   splitList(result.Writer),
+		Actors:    splitList(result.Actors),
+		Language:  notAvailable(result.Language),
+		Country:   notAvailable(result.Country),
+		Poster:    notAvailable(result.Poster),
+		ImdbID:    notAvailable(result.ImdbID),
+	}, nil
+}
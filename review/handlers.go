@@ -0,0 +1,37 @@
+package review
+
+import (
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/review"
+	"github.com/movieManagement/swagger"
+)
+
+// Configure configures the review service
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.ReviewListReviewsHandler = review.ListReviewsHandlerFunc(func(params review.ListReviewsParams) middleware.Responder {
+		result, err := service.ListReviews(params.HTTPRequest.Context(), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ListReviews :: ", err)
+		}
+		return review.NewListReviewsOK().WithPayload(result)
+	})
+
+	api.ReviewCreateReviewHandler = review.CreateReviewHandlerFunc(func(params review.CreateReviewParams, principal *models.Principal) middleware.Responder {
+		result, err := service.CreateReview(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("CreateReview :: ", err)
+		}
+		return review.NewCreateReviewCreated().WithPayload(result)
+	})
+
+	api.ReviewUpdateReviewHandler = review.UpdateReviewHandlerFunc(func(params review.UpdateReviewParams, principal *models.Principal) middleware.Responder {
+		result, err := service.UpdateReview(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("UpdateReview :: ", err)
+		}
+		return review.NewUpdateReviewOK().WithPayload(result)
+	})
+}
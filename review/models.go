@@ -0,0 +1,39 @@
+package review
+
+import (
+	"database/sql"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/gen/models"
+)
+
+const (
+	// minScore and maxScore bound the score of a review
+	minScore = 1
+	maxScore = 10
+	// maxText is the maximum length of the text of a review, in characters
+	maxText = 5000
+)
+
+// SQLReview . . .
+type SQLReview struct {
+	ID        int64
+	MovieID   string
+	Author    string
+	Score     int64
+	Body      sql.NullString
+	CreatedAt strfmt.DateTime
+	UpdatedAt strfmt.DateTime
+}
+
+func (sql *SQLReview) toReview() *models.Review {
+	return &models.Review{
+		ID:        sql.ID,
+		MovieID:   sql.MovieID,
+		Author:    sql.Author,
+		Score:     sql.Score,
+		Text:      sql.Body.String,
+		CreatedAt: sql.CreatedAt,
+		UpdatedAt: sql.UpdatedAt,
+	}
+}
@@ -0,0 +1,255 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+	"github.com/lib/pq"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/movie"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ReviewTable . . .
+	ReviewTable = "public.movie_reviews as r"
+
+	// pqUniqueViolation is the postgres error code of a unique constraint violation
+	pqUniqueViolation = "23505"
+)
+
+var reviewReturnFields = []string{
+	"r.id as ID",
+	"COALESCE(mv.sfid, '') as MovieID",
+	"r.subject as Author",
+	"r.score as Score",
+	"r.body as Body",
+	"r.created_at as CreatedAt",
+	"r.updated_at as UpdatedAt",
+}
+
+// Repository interface includes a list of supported review operations
+type Repository interface {
+	ListReviews(ctx context.Context, movieID string, offset, pageSize int64) ([]*models.Review, int64, error)
+	CreateReview(ctx context.Context, movieID, subject string, score int64, text string) (*models.Review, error)
+	UpdateReview(ctx context.Context, movieID string, id int64, subject string, score int64, text string) (*models.Review, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// ListReviews returns a page of the reviews of the movie, the most recently updated first, and
+// the total number of its reviews. errs.ErrNotFound when the movie is unknown or soft deleted
+func (repo *repository) ListReviews(ctx context.Context, movieID string, offset, pageSize int64) ([]*models.Review, int64, error) {
+	logrus.Debugf("ListReviews repo")
+	var serial int64
+	err := sqlz.Newx(repo.GetDB()).
+		Select(`mv."Id"`).
+		From(movie.MovieTable).
+		Where(movie.IDCondition(movieID), sqlz.IsNull("mv.deleted_at")).
+		GetRow(&serial)
+	if err == sql.ErrNoRows {
+		return nil, 0, errors.Wrap(errs.ErrNotFound, "ListReviews")
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, 0, errors.Wrap(err, "ListReviews.SelectMovieQuery")
+	}
+
+	count, err := selectReviews(sqlz.Newx(repo.GetDB())).
+		Where(sqlz.Eq("r.movie_id", serial)).
+		GetCount()
+	if err != nil {
+		log.Error(err)
+		return nil, 0, errors.Wrap(err, "ListReviews.GetCount")
+	}
+
+	sqlReviews := []SQLReview{}
+	err = selectReviews(sqlz.Newx(repo.GetDB())).
+		Where(sqlz.Eq("r.movie_id", serial)).
+		OrderBy(sqlz.Desc("r.updated_at"), sqlz.Desc("r.id")).
+		Limit(pageSize).
+		Offset(offset).
+		GetAll(&sqlReviews)
+	if err != nil {
+		log.Error(err)
+		return nil, 0, errors.Wrap(err, "ListReviews.SelectQuery")
+	}
+
+	reviews := make([]*models.Review, 0, len(sqlReviews))
+	for _, sqlReview := range sqlReviews {
+		reviews = append(reviews, sqlReview.toReview())
+	}
+	return reviews, count, nil
+}
+
+// CreateReview stores the review of the subject and updates the community rating of the movie in
+// the same transaction. errs.ErrConflict when the subject has already reviewed the movie
+func (repo *repository) CreateReview(ctx context.Context, movieID, subject string, score int64, text string) (*models.Review, error) {
+	logrus.Debugf("CreateReview repo")
+	sqlReview := SQLReview{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		serial, err := lockMovie(tx, movieID)
+		if err != nil {
+			return err
+		}
+
+		var id int64
+		err = tx.InsertInto("public.movie_reviews").
+			ValueMap(map[string]interface{}{
+				"movie_id": serial,
+				"subject":  subject,
+				"score":    score,
+				"body":     nullIfEmpty(text),
+			}).
+			Returning("id").
+			GetRow(&id)
+		if err != nil {
+			return errors.Wrap(conflictOrErr(err), "InsertQuery")
+		}
+
+		err = updateCommunityRating(tx, serial)
+		if err != nil {
+			return err
+		}
+		return selectReviews(tx).Where(sqlz.Eq("r.id", id)).GetRow(&sqlReview)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "CreateReview")
+	}
+
+	return sqlReview.toReview(), nil
+}
+
+// UpdateReview replaces the score and text of the review of the subject and updates the
+// community rating of the movie in the same transaction. errs.ErrForbidden when the review is
+// another subject's
+func (repo *repository) UpdateReview(ctx context.Context, movieID string, id int64, subject string, score int64, text string) (*models.Review, error) {
+	logrus.Debugf("UpdateReview repo")
+	sqlReview := SQLReview{}
+
+	err := sqlz.Newx(repo.GetDB()).Transactional(func(tx *sqlz.Tx) error {
+		serial, err := lockMovie(tx, movieID)
+		if err != nil {
+			return err
+		}
+
+		var author string
+		err = tx.Select("subject").
+			From("public.movie_reviews").
+			Where(sqlz.Eq("id", id), sqlz.Eq("movie_id", serial)).
+			GetRow(&author)
+		if err == sql.ErrNoRows {
+			return errs.ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "SelectReviewQuery")
+		}
+		if author != subject {
+			return errors.Wrap(errs.ErrForbidden, "only the author edits a review")
+		}
+
+		_, err = tx.Update("public.movie_reviews").
+			Set("score", score).
+			Set("body", nullIfEmpty(text)).
+			Set("updated_at", sqlz.Indirect("now()")).
+			Where(sqlz.Eq("id", id)).
+			Exec()
+		if err != nil {
+			return errors.Wrap(err, "UpdateQuery")
+		}
+
+		err = updateCommunityRating(tx, serial)
+		if err != nil {
+			return err
+		}
+		return selectReviews(tx).Where(sqlz.Eq("r.id", id)).GetRow(&sqlReview)
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "UpdateReview")
+	}
+
+	return sqlReview.toReview(), nil
+}
+
+// querier is the database, or a transaction, the reviews are selected with
+type querier interface {
+	Select(cols ...string) *sqlz.SelectStmt
+}
+
+// selectReviews selects the reviews with the SFID of their movie
+func selectReviews(db querier) *sqlz.SelectStmt {
+	return db.Select(reviewReturnFields...).
+		From(ReviewTable).
+		InnerJoin(movie.MovieTable, sqlz.Eq(`mv."Id"`, sqlz.Indirect("r.movie_id")))
+}
+
+// lockMovie locks the movie until the end of the transaction, so the concurrent reviews of the
+// movie update its community rating one after the other. errs.ErrNotFound when the movie is
+// unknown or soft deleted
+func lockMovie(tx *sqlz.Tx, movieID string) (int64, error) {
+	var serial int64
+	err := tx.Select(`mv."Id"`).
+		From(movie.MovieTable).
+		Where(movie.IDCondition(movieID), sqlz.IsNull("mv.deleted_at")).
+		Lock(sqlz.ForUpdate()).
+		GetRow(&serial)
+	if err == sql.ErrNoRows {
+		return 0, errs.ErrNotFound
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "SelectMovieQuery")
+	}
+	return serial, nil
+}
+
+// updateCommunityRating recomputes the average score and the number of the reviews of the movie.
+// The last modification date of the movie is kept, a review does not change the movie version
+func updateCommunityRating(tx *sqlz.Tx, serial int64) error {
+	_, err := tx.Update(movie.MovieTable).
+		Set("community_rating", sqlz.Indirect(`(SELECT round(avg(r.score), 2) FROM public.movie_reviews r WHERE r.movie_id = mv."Id")`)).
+		Set("community_rating_count", sqlz.Indirect(`(SELECT count(*) FROM public.movie_reviews r WHERE r.movie_id = mv."Id")`)).
+		Where(sqlz.Eq(`mv."Id"`, serial)).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "UpdateCommunityRatingQuery")
+	}
+	return nil
+}
+
+// conflictOrErr maps the unique constraint violations to errs.ErrConflict
+func conflictOrErr(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pqUniqueViolation {
+		return errors.Wrap(errs.ErrConflict, "movie already reviewed")
+	}
+	return err
+}
+
+// nullIfEmpty returns nil for an empty value so the column is stored as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
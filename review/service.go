@@ -0,0 +1,119 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/swag"
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/review"
+	"github.com/pkg/errors"
+)
+
+// Service interface is a list of services for the reviews of the movies
+type Service interface {
+	ListReviews(ctx context.Context, in *review.ListReviewsParams) (*models.ReviewList, error)
+	CreateReview(ctx context.Context, in *review.CreateReviewParams) (*models.Review, error)
+	UpdateReview(ctx context.Context, in *review.UpdateReviewParams) (*models.Review, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// New is a simple helper function to create a service instance
+func New(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// ListReviews service definition
+func (s *service) ListReviews(ctx context.Context, in *review.ListReviewsParams) (*models.ReviewList, error) {
+	log.Debugf("entered service ListReviews")
+	offset, err := strconv.ParseInt(swag.StringValue(in.Offset), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ListReviews: offset must be a non-negative integer")
+	}
+	pageSize, err := strconv.ParseInt(swag.StringValue(in.PageSize), 10, 64)
+	if err != nil || pageSize < 1 {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ListReviews: pageSize must be a positive integer")
+	}
+
+	reviews, count, err := s.repo.ListReviews(ctx, in.ID, offset, pageSize)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ListReviews")
+	}
+
+	return &models.ReviewList{
+		Data: reviews,
+		Metadata: &models.ListMetadata{
+			Offset:    offset,
+			PageSize:  pageSize,
+			TotalSize: count,
+		},
+	}, nil
+}
+
+// CreateReview service definition
+func (s *service) CreateReview(ctx context.Context, in *review.CreateReviewParams) (*models.Review, error) {
+	log.Debugf("entered service CreateReview")
+	subject, err := auth.UserSubject(ctx, auth.ReviewMovies)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.CreateReview")
+	}
+	score, text, err := reviewFields(in.Review)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.CreateReview")
+	}
+
+	result, err := s.repo.CreateReview(ctx, in.ID, subject, score, text)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.CreateReview")
+	}
+	return result, nil
+}
+
+// UpdateReview service definition
+func (s *service) UpdateReview(ctx context.Context, in *review.UpdateReviewParams) (*models.Review, error) {
+	log.Debugf("entered service UpdateReview")
+	subject, err := auth.UserSubject(ctx, auth.ReviewMovies)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.UpdateReview")
+	}
+	score, text, err := reviewFields(in.Review)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.UpdateReview")
+	}
+
+	result, err := s.repo.UpdateReview(ctx, in.ID, in.ReviewID, subject, score, text)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.UpdateReview")
+	}
+	return result, nil
+}
+
+// reviewFields checks the score and the text of the review
+func reviewFields(in *models.ReviewInput) (int64, string, error) {
+	if in == nil || in.Score == nil {
+		return 0, "", errors.Wrap(errs.ErrInvalid, "Score is required")
+	}
+	score := *in.Score
+	if score < minScore || score > maxScore {
+		return 0, "", errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Score must be between %d and %d", minScore, maxScore))
+	}
+	text := strings.TrimSpace(in.Text)
+	if utf8.RuneCountInString(text) > maxText {
+		return 0, "", errors.Wrap(errs.ErrInvalid, fmt.Sprintf("Text must not be longer than %d characters", maxText))
+	}
+	return score, text, nil
+}
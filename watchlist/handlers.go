@@ -0,0 +1,37 @@
+package watchlist
+
+import (
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations"
+	"github.com/movieManagement/gen/restapi/operations/watchlist"
+	"github.com/movieManagement/swagger"
+)
+
+// Configure configures the watchlist service
+func Configure(api *operations.MovieServiceAPI, service Service) {
+	api.WatchlistListWatchlistHandler = watchlist.ListWatchlistHandlerFunc(func(params watchlist.ListWatchlistParams, principal *models.Principal) middleware.Responder {
+		result, err := service.ListWatchlist(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("ListWatchlist :: ", err)
+		}
+		return watchlist.NewListWatchlistOK().WithPayload(result)
+	})
+
+	api.WatchlistPutWatchlistEntryHandler = watchlist.PutWatchlistEntryHandlerFunc(func(params watchlist.PutWatchlistEntryParams, principal *models.Principal) middleware.Responder {
+		result, err := service.PutWatchlistEntry(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("PutWatchlistEntry :: ", err)
+		}
+		return watchlist.NewPutWatchlistEntryOK().WithPayload(result)
+	})
+
+	api.WatchlistRemoveWatchlistEntryHandler = watchlist.RemoveWatchlistEntryHandlerFunc(func(params watchlist.RemoveWatchlistEntryParams, principal *models.Principal) middleware.Responder {
+		err := service.RemoveWatchlistEntry(auth.NewContext(params.HTTPRequest.Context(), principal), &params)
+		if err != nil {
+			return swagger.ErrorHandler("RemoveWatchlistEntry :: ", err)
+		}
+		return watchlist.NewRemoveWatchlistEntryNoContent()
+	})
+}
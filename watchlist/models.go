@@ -0,0 +1,58 @@
+package watchlist
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/movieManagement/gen/models"
+)
+
+const (
+	// StatusPlanned is the status of the movies to watch
+	StatusPlanned = "planned"
+	// StatusWatching is the status of the movies being watched
+	StatusWatching = "watching"
+	// StatusWatched is the status of the movies seen
+	StatusWatched = "watched"
+
+	// maxPriority is the highest priority of a movie
+	maxPriority = 100
+	// maxNotes is the maximum length of the notes, in characters
+	maxNotes = 2000
+)
+
+// Statuses are the statuses of the watchlist movies
+var Statuses = []string{StatusPlanned, StatusWatching, StatusWatched}
+
+// SQLEntry . . .
+type SQLEntry struct {
+	MovieID     string
+	Slug        string
+	Title       string
+	ReleaseYear sql.NullInt64
+	PosterURL   sql.NullString
+	Status      string
+	Priority    int64
+	Notes       sql.NullString
+	AddedAt     strfmt.DateTime
+	UpdatedAt   strfmt.DateTime
+}
+
+func (sql *SQLEntry) toEntry() *models.WatchlistEntry {
+	entry := models.WatchlistEntry{
+		MovieID:   sql.MovieID,
+		Slug:      sql.Slug,
+		Title:     sql.Title,
+		Poster:    sql.PosterURL.String,
+		Status:    sql.Status,
+		Priority:  sql.Priority,
+		Notes:     sql.Notes.String,
+		AddedAt:   sql.AddedAt,
+		UpdatedAt: sql.UpdatedAt,
+	}
+	if sql.ReleaseYear.Valid {
+		entry.ReleasedYear = strconv.FormatInt(sql.ReleaseYear.Int64, 10)
+	}
+	return &entry
+}
@@ -0,0 +1,183 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ido50/sqlz"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/movie"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EntryTable . . .
+	EntryTable = "public.watchlist_entries as we"
+)
+
+var entryReturnFields = []string{
+	"COALESCE(mv.sfid, '') as MovieID",
+	"COALESCE(mv.slug, '') as Slug",
+	"COALESCE(mv.title, '') as Title",
+	"mv.release_year as ReleaseYear",
+	"mv.poster_url as PosterURL",
+	"we.status as Status",
+	"we.priority as Priority",
+	"we.notes as Notes",
+	"we.added_at as AddedAt",
+	"we.updated_at as UpdatedAt",
+}
+
+// Repository interface includes a list of supported watchlist operations
+type Repository interface {
+	ListEntries(ctx context.Context, subject, status string, offset, pageSize int64) ([]*models.WatchlistEntry, int64, error)
+	PutEntry(ctx context.Context, subject, movieID, status string, priority int64, notes string) (*models.WatchlistEntry, error)
+	RemoveEntry(ctx context.Context, subject, movieID string) error
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new repository from the specified DB reference
+func NewRepository(db *sqlx.DB) Repository {
+	return &repository{
+		db: db,
+	}
+}
+
+// GetDB returns a reference to the underlying database connection
+func (repo *repository) GetDB() *sqlx.DB {
+	return repo.db
+}
+
+// ListEntries returns a page of the watchlist of the subject, optionally only the movies with the
+// status, and the total number of its movies. The soft deleted movies are left out
+func (repo *repository) ListEntries(ctx context.Context, subject, status string, offset, pageSize int64) ([]*models.WatchlistEntry, int64, error) {
+	logrus.Debugf("ListEntries repo")
+	conditions := []sqlz.WhereCondition{
+		sqlz.Eq("we.subject", subject),
+		sqlz.IsNull("mv.deleted_at"),
+	}
+	if status != "" {
+		conditions = append(conditions, sqlz.Eq("we.status", status))
+	}
+
+	count, err := repo.selectEntries().
+		Where(conditions...).
+		GetCount()
+	if err != nil {
+		log.Error(err)
+		return nil, 0, errors.Wrap(err, "ListEntries.GetCount")
+	}
+
+	sqlEntries := []SQLEntry{}
+	err = repo.selectEntries().
+		Where(conditions...).
+		OrderBy(sqlz.Desc("we.priority"), sqlz.Desc("we.added_at"), sqlz.Asc("we.movie_id")).
+		Limit(pageSize).
+		Offset(offset).
+		GetAll(&sqlEntries)
+	if err != nil {
+		log.Error(err)
+		return nil, 0, errors.Wrap(err, "ListEntries.SelectQuery")
+	}
+
+	entries := make([]*models.WatchlistEntry, 0, len(sqlEntries))
+	for _, sqlEntry := range sqlEntries {
+		entries = append(entries, sqlEntry.toEntry())
+	}
+	return entries, count, nil
+}
+
+// PutEntry adds the movie to the watchlist of the subject, or replaces the status, priority and
+// notes of the movie already on it. errs.ErrNotFound when the movie is unknown or soft deleted
+func (repo *repository) PutEntry(ctx context.Context, subject, movieID, status string, priority int64, notes string) (*models.WatchlistEntry, error) {
+	logrus.Debugf("PutEntry repo")
+	serial, err := repo.movieSerial(movieID, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "PutEntry")
+	}
+
+	_, err = repo.GetDB().ExecContext(ctx, `INSERT INTO public.watchlist_entries (subject, movie_id, status, priority, notes)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (subject, movie_id) DO UPDATE SET status = excluded.status, priority = excluded.priority, notes = excluded.notes, updated_at = now()`,
+		subject, serial, status, priority, notes)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "PutEntry.UpsertQuery")
+	}
+
+	sqlEntry := SQLEntry{}
+	err = repo.selectEntries().
+		Where(sqlz.Eq("we.subject", subject), sqlz.Eq("we.movie_id", serial)).
+		GetRow(&sqlEntry)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "PutEntry.SelectQuery")
+	}
+	return sqlEntry.toEntry(), nil
+}
+
+// RemoveEntry removes the movie, even soft deleted, from the watchlist of the subject.
+// errs.ErrNotFound when the movie is not on the watchlist
+func (repo *repository) RemoveEntry(ctx context.Context, subject, movieID string) error {
+	logrus.Debugf("RemoveEntry repo")
+	serial, err := repo.movieSerial(movieID, true)
+	if err != nil {
+		return errors.Wrap(err, "RemoveEntry")
+	}
+
+	res, err := sqlz.Newx(repo.GetDB()).
+		DeleteFrom("public.watchlist_entries").
+		Where(sqlz.Eq("subject", subject), sqlz.Eq("movie_id", serial)).
+		Exec()
+	if err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "RemoveEntry.DeleteQuery")
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RemoveEntry.RowsAffected")
+	}
+	if removed == 0 {
+		return errors.Wrap(errs.ErrNotFound, "RemoveEntry")
+	}
+	return nil
+}
+
+// selectEntries selects the watchlist entries with their movies
+func (repo *repository) selectEntries() *sqlz.SelectStmt {
+	return sqlz.Newx(repo.GetDB()).
+		Select(entryReturnFields...).
+		From(EntryTable).
+		InnerJoin(movie.MovieTable, sqlz.Eq(`mv."Id"`, sqlz.Indirect("we.movie_id")))
+}
+
+// movieSerial returns the numeric ID of the movie with the SFID, numeric ID or slug
+func (repo *repository) movieSerial(id string, includeDeleted bool) (int64, error) {
+	conditions := []sqlz.WhereCondition{movie.IDCondition(id)}
+	if !includeDeleted {
+		conditions = append(conditions, sqlz.IsNull("mv.deleted_at"))
+	}
+
+	var serial int64
+	err := sqlz.Newx(repo.GetDB()).
+		Select(`mv."Id"`).
+		From(movie.MovieTable).
+		Where(conditions...).
+		GetRow(&serial)
+	if err == sql.ErrNoRows {
+		return 0, errors.Wrap(errs.ErrNotFound, "movieSerial")
+	}
+	if err != nil {
+		log.Error(err)
+		return 0, errors.Wrap(err, "movieSerial.SelectQuery")
+	}
+	return serial, nil
+}
@@ -0,0 +1,132 @@
+package watchlist
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/swag"
+	"github.com/labstack/gommon/log"
+	"github.com/movieManagement/auth"
+	"github.com/movieManagement/errs"
+	"github.com/movieManagement/gen/models"
+	"github.com/movieManagement/gen/restapi/operations/watchlist"
+	"github.com/pkg/errors"
+)
+
+// Service interface is a list of services for the watchlists of the users
+type Service interface {
+	ListWatchlist(ctx context.Context, in *watchlist.ListWatchlistParams) (*models.WatchlistEntryList, error)
+	PutWatchlistEntry(ctx context.Context, in *watchlist.PutWatchlistEntryParams) (*models.WatchlistEntry, error)
+	RemoveWatchlistEntry(ctx context.Context, in *watchlist.RemoveWatchlistEntryParams) error
+}
+
+type service struct {
+	repo Repository
+}
+
+// New is a simple helper function to create a service instance
+func New(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// ListWatchlist service definition
+func (s *service) ListWatchlist(ctx context.Context, in *watchlist.ListWatchlistParams) (*models.WatchlistEntryList, error) {
+	log.Debugf("entered service ListWatchlist")
+	subject, err := auth.UserSubject(ctx, auth.ManageWatchlist)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.ListWatchlist")
+	}
+
+	offset, err := strconv.ParseInt(swag.StringValue(in.Offset), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ListWatchlist: offset must be a non-negative integer")
+	}
+	pageSize, err := strconv.ParseInt(swag.StringValue(in.PageSize), 10, 64)
+	if err != nil || pageSize < 1 {
+		return nil, errors.Wrap(errs.ErrInvalid, "service.ListWatchlist: pageSize must be a positive integer")
+	}
+	status := swag.StringValue(in.Status)
+	if status != "" && !validStatus(status) {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("service.ListWatchlist: unknown status %q", status))
+	}
+
+	entries, count, err := s.repo.ListEntries(ctx, subject, status, offset, pageSize)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.ListWatchlist")
+	}
+
+	return &models.WatchlistEntryList{
+		Data: entries,
+		Metadata: &models.ListMetadata{
+			Offset:    offset,
+			PageSize:  pageSize,
+			TotalSize: count,
+		},
+	}, nil
+}
+
+// PutWatchlistEntry service definition
+func (s *service) PutWatchlistEntry(ctx context.Context, in *watchlist.PutWatchlistEntryParams) (*models.WatchlistEntry, error) {
+	log.Debugf("entered service PutWatchlistEntry")
+	subject, err := auth.UserSubject(ctx, auth.ManageWatchlist)
+	if err != nil {
+		return nil, errors.Wrap(err, "service.PutWatchlistEntry")
+	}
+
+	status := StatusPlanned
+	var priority int64
+	var notes string
+	if in.Entry != nil {
+		if swag.StringValue(in.Entry.Status) != "" {
+			status = *in.Entry.Status
+		}
+		priority = swag.Int64Value(in.Entry.Priority)
+		notes = strings.TrimSpace(in.Entry.Notes)
+	}
+	if !validStatus(status) {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("service.PutWatchlistEntry: unknown status %q", status))
+	}
+	if priority < 0 || priority > maxPriority {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("service.PutWatchlistEntry: Priority must be between 0 and %d", maxPriority))
+	}
+	if utf8.RuneCountInString(notes) > maxNotes {
+		return nil, errors.Wrap(errs.ErrInvalid, fmt.Sprintf("service.PutWatchlistEntry: Notes must not be longer than %d characters", maxNotes))
+	}
+
+	entry, err := s.repo.PutEntry(ctx, subject, in.ID, status, priority, notes)
+	if err != nil {
+		log.Error(err)
+		return nil, errors.Wrap(err, "service.PutWatchlistEntry")
+	}
+	return entry, nil
+}
+
+// RemoveWatchlistEntry service definition
+func (s *service) RemoveWatchlistEntry(ctx context.Context, in *watchlist.RemoveWatchlistEntryParams) error {
+	log.Debugf("entered service RemoveWatchlistEntry")
+	subject, err := auth.UserSubject(ctx, auth.ManageWatchlist)
+	if err != nil {
+		return errors.Wrap(err, "service.RemoveWatchlistEntry")
+	}
+
+	if err := s.repo.RemoveEntry(ctx, subject, in.ID); err != nil {
+		log.Error(err)
+		return errors.Wrap(err, "service.RemoveWatchlistEntry")
+	}
+	return nil
+}
+
+func validStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}